@@ -0,0 +1,197 @@
+package goboot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestIDHeader carries the request ID, it is generated by AccessLog when
+// the client did not send one.
+const RequestIDHeader = "X-Request-ID"
+
+// Access log formats for http.log.format.
+const (
+	AccessLogCommon   = "common"
+	AccessLogCombined = "combined"
+	AccessLogJSON     = "json"
+)
+
+// accessEntry is a JSON access log line.
+type accessEntry struct {
+	Time      string  `json:"time"`
+	RemoteIP  string  `json:"remote_ip"`
+	User      string  `json:"user,omitempty"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Query     string  `json:"query,omitempty"`
+	Proto     string  `json:"proto"`
+	Status    int     `json:"status"`
+	Bytes     int64   `json:"bytes"`
+	Latency   float64 `json:"latency"`
+	Referer   string  `json:"referer,omitempty"`
+	UserAgent string  `json:"user_agent,omitempty"`
+	RequestID string  `json:"request_id"`
+}
+
+// RequestID returns the ID of r, set by AccessLog.
+func RequestID(r *http.Request) string {
+	return r.Header.Get(RequestIDHeader)
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog logs the requests of next with the default App, see
+// App.AccessLog.
+func AccessLog(next http.Handler) http.Handler {
+	return defaultApp.AccessLog(next)
+}
+
+// AccessLog returns middleware writing a line per request to http.log.output,
+// kept apart from the application log: without it, or when it is "off",
+// next is returned as is. http.log.format is
+// "common" or "combined" for the Apache formats, or "json" for JSON lines
+// that also hold the latency and the request ID; the default is "combined".
+//
+// The remote IP is the peer of the connection. X-Forwarded-For is only
+// honoured for requests coming from the trusted proxies listed by
+// http.proxies, or from any peer when it is "*".
+// The request ID is read from X-Request-ID, or generated, and echoed in the
+// response.
+func (a *App) AccessLog(next http.Handler) http.Handler {
+	a.httpLogOnce.Do(a.initHTTPLog)
+	w := a.httpLogWriter
+	if w == nil {
+		return next
+	}
+
+	al := &accessLogger{w: w, format: AccessLogCombined, filter: a.LogRedactFilter}
+	if a.Config != nil {
+		al.format = a.Config.MustString(IniHttpLogFormat, AccessLogCombined)
+		list := a.Config.MustStringArray(IniHttpProxies, ",")
+		if len(list) == 1 && strings.TrimSpace(list[0]) == "*" {
+			al.trustAll = true
+		} else if proxies, err := parseCIDRs(list); err != nil {
+			if a.Log != nil {
+				a.Log.Errorf("%s: %v, X-Forwarded-For is ignored", IniHttpProxies, err)
+			}
+		} else {
+			al.proxies = proxies
+		}
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if RequestID(r) == "" {
+			r.Header.Set(RequestIDHeader, newRequestID())
+		}
+		rw.Header().Set(RequestIDHeader, RequestID(r))
+
+		start := time.Now()
+		rec := &responseWriter{ResponseWriter: rw}
+		next.ServeHTTP(rec, r)
+		al.log(r, rec.Status(), rec.size, start)
+	})
+}
+
+type accessLogger struct {
+	mu      sync.Mutex
+	w       io.Writer
+	format  string
+	proxies []*net.IPNet // peers whose X-Forwarded-For is honoured
+	filter  *RedactFilter
+
+	// trustAll honours X-Forwarded-For from any peer, see http.proxies.
+	trustAll bool
+}
+
+func (al *accessLogger) log(r *http.Request, status int, size int64, start time.Time) {
+	uri := r.URL.RequestURI()
+	query := r.URL.RawQuery
+	if al.filter != nil {
+		uri = al.filter.RedactString(uri)
+		query = al.filter.RedactString(query)
+	}
+	user := "-"
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		user = u
+	}
+
+	var line string
+	switch al.format {
+	case AccessLogJSON:
+		e := accessEntry{
+			Time:      start.Format(time.RFC3339Nano),
+			RemoteIP:  al.remoteIP(r),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Query:     query,
+			Proto:     r.Proto,
+			Status:    status,
+			Bytes:     size,
+			Latency:   time.Since(start).Seconds(),
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+			RequestID: RequestID(r),
+		}
+		if user != "-" {
+			e.User = user
+		}
+		b, _ := json.Marshal(e)
+		line = string(b) + "\n"
+	default:
+		bytes := "-"
+		if size > 0 {
+			bytes = fmt.Sprint(size)
+		}
+		line = fmt.Sprintf("%s - %s [%s] %q %d %s", al.remoteIP(r), user, start.Format("02/Jan/2006:15:04:05 -0700"),
+			r.Method+" "+uri+" "+r.Proto, status, bytes)
+		if al.format != AccessLogCommon {
+			line += fmt.Sprintf(" %q %q", r.Referer(), r.UserAgent())
+		}
+		line += "\n"
+	}
+
+	al.mu.Lock()
+	io.WriteString(al.w, line)
+	al.mu.Unlock()
+}
+
+// remoteIP returns the client address, honouring X-Forwarded-For when the
+// peer is a trusted proxy: the rightmost address not belonging to one is the
+// client. When every peer is trusted the leftmost address is the client.
+func (al *accessLogger) remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	xff := r.Header.Get("X-Forwarded-For")
+	if xff == "" {
+		return host
+	}
+	hops := strings.Split(xff, ",")
+	if al.trustAll {
+		return strings.TrimSpace(hops[0])
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !containsIP(al.proxies, ip) {
+		return host
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if ip := net.ParseIP(hop); ip == nil || !containsIP(al.proxies, ip) || i == 0 {
+			return hop
+		}
+	}
+	return host
+}
@@ -0,0 +1,138 @@
+package goboot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func accessLogApp(t *testing.T, keys map[string]string) (*App, string) {
+	a := newTestApp(t)
+	out := filepath.Join(t.TempDir(), "access.log")
+	a.Config.RunModeSection.NewKey(IniHttpLogOutput, out)
+	for k, v := range keys {
+		a.Config.RunModeSection.NewKey(k, v)
+	}
+	return a, out
+}
+
+func serveAccessLog(t *testing.T, a *App, r *http.Request) *httptest.ResponseRecorder {
+	h := a.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("hello"))
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	a, out := accessLogApp(t, map[string]string{IniHttpProxies: "10.0.0.0/8"})
+
+	r := httptest.NewRequest("GET", "/users?token=abc&page=2", nil)
+	r.RemoteAddr = "10.0.0.1:4321"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.7")
+	r.Header.Set("User-Agent", "curl/8")
+	a.LogRedactFilter, _ = NewRedactFilter(DefaultRedactKeys, nil)
+	rec := serveAccessLog(t, a, r)
+
+	if rec.Header().Get(RequestIDHeader) == "" {
+		t.Error("no request id in response")
+	}
+	b, _ := os.ReadFile(out)
+	re := regexp.MustCompile(`^1\.2\.3\.4 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [-+]\d{4}\] "GET /users\?token=\*+&page=2 HTTP/1\.1" 202 5 "" "curl/8"\n$`)
+	if !re.Match(b) {
+		t.Errorf("line %q", b)
+	}
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	a, out := accessLogApp(t, map[string]string{IniHttpLogFormat: AccessLogJSON})
+
+	r := httptest.NewRequest("POST", "/orders", nil)
+	r.RemoteAddr = "192.168.1.5:1000"
+	r.Header.Set(RequestIDHeader, "req-1")
+	serveAccessLog(t, a, r)
+
+	// Without http.proxies X-Forwarded-For is ignored, "*" trusts any peer.
+	for _, proxies := range []string{"", "*"} {
+		a.Config.RunModeSection.NewKey(IniHttpProxies, proxies)
+		r = httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-Forwarded-For", "8.8.8.8, 10.0.0.7")
+		serveAccessLog(t, a, r)
+	}
+
+	b, _ := os.ReadFile(out)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("%d lines: %q", len(lines), b)
+	}
+	var e accessEntry
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Method != "POST" || e.Path != "/orders" || e.Status != 202 || e.Bytes != 5 || e.RemoteIP != "192.168.1.5" || e.RequestID != "req-1" {
+		t.Errorf("entry %+v", e)
+	}
+	for i, want := range []string{"192.0.2.1", "8.8.8.8"} {
+		if json.Unmarshal([]byte(lines[i+1]), &e); e.RemoteIP != want {
+			t.Errorf("remote ip %q, want %q", e.RemoteIP, want)
+		}
+	}
+}
+
+func TestAccessLogOutput(t *testing.T) {
+	a := newTestApp(t)
+	next := http.NewServeMux()
+	if h := a.AccessLog(next); h != http.Handler(next) {
+		t.Error("access log enabled without http.log.output")
+	}
+}
+
+func TestAccessLogHijack(t *testing.T) {
+	a, out := accessLogApp(t, nil)
+	h := a.AccessLog(a.DumpHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\nhello")
+		brw.Flush()
+	})))
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		close(done)
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: x\r\nConnection: Upgrade\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("response %v, %v", resp, err)
+	}
+	<-done
+	if b, _ := os.ReadFile(out); !strings.Contains(string(b), `"GET /ws HTTP/1.1" 101`) {
+		t.Errorf("line %q", b)
+	}
+
+	rw := &responseWriter{ResponseWriter: httptest.NewRecorder()}
+	if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("hijack of a recorder: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package goboot
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/e2u/goboot/metrics"
+)
+
+// adminStopOrder makes the admin server the last thing to stop, so health
+// endpoints keep answering while the other stop hooks run.
+const adminStopOrder = math.MinInt32
+
+// RegisterAdminHandler serves h for pattern on the admin server, behind the
+// same authentication and allowlist as pprof. Handlers must be registered
+// before Startup.
+func RegisterAdminHandler(pattern string, h http.Handler) {
+	defaultApp.RegisterAdminHandler(pattern, h)
+}
+
+// RegisterAdminHandlerFunc is RegisterAdminHandler for a handler function.
+func RegisterAdminHandlerFunc(pattern string, f func(http.ResponseWriter, *http.Request)) {
+	defaultApp.RegisterAdminHandler(pattern, http.HandlerFunc(f))
+}
+
+func (a *App) RegisterAdminHandler(pattern string, h http.Handler) {
+	a.adminMu.Lock()
+	a.adminHandlers[pattern] = h
+	a.adminMu.Unlock()
+}
+
+func (a *App) RegisterAdminHandlerFunc(pattern string, f func(http.ResponseWriter, *http.Request)) {
+	a.RegisterAdminHandler(pattern, http.HandlerFunc(f))
+}
+
+func (a *App) newAdminMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/metrics", metrics.Handler())
+	a.RegisterHealthHandlers(mux)
+
+	a.adminMu.Lock()
+	for pattern, h := range a.adminHandlers {
+		mux.Handle(pattern, h)
+	}
+	a.adminMu.Unlock()
+	return mux
+}
+
+// adminGuard protects the admin endpoints with an IP allowlist and, when
+// configured, a bearer token or basic auth credentials.
+type adminGuard struct {
+	allow    []*net.IPNet
+	user     string
+	password string
+	token    string
+	next     http.Handler
+}
+
+func newAdminGuard(c *ConfigContext, next http.Handler) (*adminGuard, error) {
+	g := &adminGuard{
+		user:     c.MustString(IniAdminUser),
+		password: c.MustString(IniAdminPassword),
+		token:    c.MustString(IniAdminToken),
+		next:     next,
+	}
+	allow, err := parseCIDRs(c.MustStringArray(IniAdminAllow, ","))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", IniAdminAllow, err)
+	}
+	g.allow = allow
+	return g, nil
+}
+
+// parseCIDRs parses networks in CIDR notation, a single IP is a network of
+// one address.
+func parseCIDRs(list []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, a := range list {
+		if a == "" {
+			continue
+		}
+		if !strings.Contains(a, "/") {
+			if ip := net.ParseIP(a); ip != nil && ip.To4() != nil {
+				a += "/32"
+			} else {
+				a += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(a)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *adminGuard) allowed(remoteAddr string) bool {
+	if len(g.allow) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && containsIP(g.allow, ip)
+}
+
+func (g *adminGuard) authorized(r *http.Request) bool {
+	if g.token == "" && g.user == "" {
+		return true
+	}
+	if g.token != "" {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Bearer ") && secureEqual(auth[len("Bearer "):], g.token) {
+			return true
+		}
+	}
+	if g.user != "" {
+		if user, password, ok := r.BasicAuth(); ok && secureEqual(user, g.user) && secureEqual(password, g.password) {
+			return true
+		}
+	}
+	return false
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func (g *adminGuard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !g.allowed(r.RemoteAddr) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	if !g.authorized(r) {
+		if g.user != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	g.next.ServeHTTP(w, r)
+}
+
+// startAdminServer listens on admin.addr, or pprof.addr for older
+// configurations, and serves pprof, /metrics, the health endpoints and the
+// registered admin handlers. Nothing is started when neither key is set.
+func (a *App) startAdminServer() error {
+	addr := a.Config.MustString(IniAdminAddr, a.Config.MustString(IniPprofAddr))
+	if addr == "" {
+		return nil
+	}
+
+	guard, err := newAdminGuard(a.Config, a.newAdminMux())
+	if err != nil {
+		return fmt.Errorf("admin server: %v", err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("admin server: %v", err)
+	}
+
+	srv := &http.Server{Handler: guard}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			a.Log.Error("admin server:", err)
+		}
+	}()
+	a.OnAppStopContext(func(ctx context.Context) error {
+		return srv.Shutdown(ctx)
+	}, adminStopOrder)
+
+	a.Log.Infof("admin server listening on %s, pprof at http://%s/debug/pprof/", ln.Addr(), ln.Addr())
+	return nil
+}
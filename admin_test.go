@@ -0,0 +1,59 @@
+package goboot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminGuard(t *testing.T) {
+	cfg := NewConfigWithoutFile("test")
+	cfg.RunModeSection.NewKey(IniAdminToken, "s3cret")
+	cfg.RunModeSection.NewKey(IniAdminUser, "ops")
+	cfg.RunModeSection.NewKey(IniAdminPassword, "pw")
+	cfg.RunModeSection.NewKey(IniAdminAllow, "10.0.0.0/8, 127.0.0.1")
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	g, err := newAdminGuard(cfg, ok)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		remote string
+		auth   func(r *http.Request)
+		code   int
+	}{
+		{"192.168.1.1:5000", func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") }, http.StatusForbidden},
+		{"127.0.0.1:5000", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"127.0.0.1:5000", func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") }, http.StatusUnauthorized},
+		{"10.1.2.3:5000", func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") }, http.StatusOK},
+		{"10.1.2.3:5000", func(r *http.Request) { r.SetBasicAuth("ops", "pw") }, http.StatusOK},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/debug/pprof/", nil)
+		r.RemoteAddr = c.remote
+		c.auth(r)
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, r)
+		if rec.Code != c.code {
+			t.Errorf("%s: got %d, want %d", c.remote, rec.Code, c.code)
+		}
+	}
+}
+
+func TestAdminMux(t *testing.T) {
+	a := newTestApp(t)
+	a.RegisterAdminHandlerFunc("/admin/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	})
+	mux := a.newAdminMux()
+
+	for path, want := range map[string]int{"/admin/ping": 200, "/livez": 200, "/debug/pprof/": 200, "/nothing": 404} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: got %d, want %d", path, rec.Code, want)
+		}
+	}
+}
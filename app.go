@@ -0,0 +1,203 @@
+package goboot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/e2u/goboot/jobs"
+	logging "github.com/op/go-logging"
+)
+
+// App owns a configuration, a logger, the lifecycle hooks, the dependency
+// container, the health checks, a job scheduler and the admin server. Several
+// Apps can live in one process, e.g. to test two configurations side by side;
+// the package level functions and the Config and Log variables belong to a
+// default App.
+type App struct {
+	Config *ConfigContext
+	Log    Logger
+
+	// LogRedactFilter masks secrets before records reach the backend of
+	// Log, it is nil when log.redact is false.
+	LogRedactFilter *RedactFilter
+
+	// Scheduler is started by Startup and stopped by Shutdown.
+	Scheduler *jobs.Scheduler
+
+	// Binders bind the params parsed by ParseParams, overriding the global
+	// binders.
+	Binders *BinderRegistry
+
+	runMode    string
+	configFile string
+	logBackend logging.LeveledBackend
+
+	httpLogOnce   sync.Once
+	httpLog       Logger
+	httpLogWriter io.Writer
+
+	httpClientsMu sync.Mutex
+	httpClients   map[string]*http.Client
+
+	mu           sync.Mutex
+	startupHooks StartupHooks
+	stopHooks    StopHooks
+
+	stopOnce     sync.Once
+	stopCh       chan struct{}
+	shutdownOnce sync.Once
+	shutdownErr  error
+
+	healthMu     sync.RWMutex
+	healthChecks map[string]*healthCheck
+	ready        int32
+
+	adminMu       sync.Mutex
+	adminHandlers map[string]http.Handler
+
+	container container
+}
+
+// Option configures an App created by New.
+type Option func(a *App)
+
+// WithMode sets the run mode selecting the config section, the default is
+// "auto".
+func WithMode(mode string) Option {
+	return func(a *App) { a.runMode = mode }
+}
+
+// WithConfigFile reads the configuration from file instead of conf/app.conf.
+func WithConfigFile(file string) Option {
+	return func(a *App) { a.configFile = file }
+}
+
+// WithConfig uses c instead of reading a configuration file.
+func WithConfig(c *ConfigContext) Option {
+	return func(a *App) { a.Config = c }
+}
+
+// WithLogger uses l instead of a logger built from the log.* keys.
+func WithLogger(l Logger) Option {
+	return func(a *App) { a.Log = l }
+}
+
+// WithScheduler runs the jobs of the App on s, e.g. jobs.MainScheduler() to
+// share the scheduler of the jobs package functions.
+func WithScheduler(s *jobs.Scheduler) Option {
+	return func(a *App) { a.Scheduler = s }
+}
+
+// defaultApp backs the package level functions. It is created before Init so
+// that hooks registered from init functions are kept.
+var defaultApp = newApp(jobs.MainScheduler())
+
+func newApp(s *jobs.Scheduler) *App {
+	return &App{
+		runMode:       "auto",
+		Scheduler:     s,
+		Binders:       NewBinderRegistry(nil),
+		stopCh:        make(chan struct{}),
+		healthChecks:  make(map[string]*healthCheck),
+		adminHandlers: make(map[string]http.Handler),
+	}
+}
+
+// New returns an App configured by opts. The configuration is read from
+// conf/app.conf when it exists and no other source is given, the logger is
+// built from it unless WithLogger is used.
+func New(opts ...Option) *App {
+	a := newApp(nil)
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.Config == nil {
+		a.loadConfig(a.configFile)
+	}
+	if a.Log == nil {
+		a.InitLogger()
+	}
+	if a.Scheduler == nil {
+		a.Scheduler = jobs.NewScheduler()
+	}
+	return a
+}
+
+// Default returns the App behind the package level functions.
+func Default() *App {
+	return syncDefault()
+}
+
+// syncDefault picks up values assigned to the package level Config and Log
+// since they were last read.
+func syncDefault() *App {
+	if defaultApp.Config != Config {
+		defaultApp.Config = Config
+	}
+	if defaultApp.Log != Log {
+		defaultApp.Log = Log
+	}
+	return defaultApp
+}
+
+// loadConfig reads file, or conf/app.conf when file is empty and it exists.
+func (a *App) loadConfig(file string) {
+	if file == "" && fileExists("conf/app.conf") {
+		file = "conf/app.conf"
+	}
+	if file != "" {
+		a.Config = NewConfigWithFile(file, a.runMode)
+	} else {
+		a.Config = NewConfigWithoutFile(a.runMode)
+	}
+}
+
+// Startup applies the time formats of the config, starts the scheduler, the
+// upload sweeper and the admin server, runs the startup hooks and returns
+// their errors. The app reports ready once every hook succeeded.
+func (a *App) Startup() error {
+	return a.startup(context.Background())
+}
+
+// startup is Startup, cancelling ctx cancels the startup hooks, see Run.
+func (a *App) startup(ctx context.Context) error {
+	if err := a.setTimeFormats(); err != nil {
+		return err
+	}
+	a.Scheduler.Start()
+	a.startUploadSweeper()
+	if err := a.startAdminServer(); err != nil {
+		return err
+	}
+	if err := a.runStartupHooks(ctx); err != nil {
+		return err
+	}
+	a.setReady(true)
+	return nil
+}
+
+// setTimeFormats applies the format.* keys of the config: the default App
+// sets the global formats with SetTimeFormats, the other Apps register a
+// time.Time binder with their formats in Binders.
+func (a *App) setTimeFormats() error {
+	if a.Config == nil {
+		return nil
+	}
+	if a == defaultApp {
+		return SetTimeFormats(a.Config)
+	}
+	tf, err := loadTimeFormats(a.Config)
+	if err != nil {
+		return err
+	}
+	a.Binders.Register(reflect.TypeOf(time.Time{}), newTimeBinder(func() timeFormats { return tf }))
+	return nil
+}
+
+func (a *App) RunMode() string {
+	return a.runMode
+}
@@ -0,0 +1,72 @@
+package goboot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logging "github.com/op/go-logging"
+)
+
+// newTestApp returns an App with an empty test configuration, shut down when
+// the test ends.
+func newTestApp(t *testing.T) *App {
+	a := New(WithConfig(NewConfigWithoutFile("test")), WithLogger(logging.MustGetLogger("test")))
+	t.Cleanup(func() { a.Shutdown() })
+	return a
+}
+
+// withTestLog sets Log for code using the package level logger, such as the
+// binders.
+func withTestLog(t *testing.T) {
+	prev := Log
+	Log = logging.MustGetLogger("test")
+	t.Cleanup(func() { Log = prev })
+}
+
+func TestAppsAreIndependent(t *testing.T) {
+	dir := t.TempDir()
+	newApp := func(name string) *App {
+		cfg := NewConfigWithoutFile("test")
+		cfg.RunModeSection.NewKey("app.name", name)
+		cfg.RunModeSection.NewKey(IniLogOutput, filepath.Join(dir, name+".log"))
+		cfg.RunModeSection.NewKey(IniLevel, "INFO")
+		a := New(WithConfig(cfg))
+		t.Cleanup(func() { a.Shutdown() })
+		return a
+	}
+	one, two := newApp("one"), newApp("two")
+
+	started := ""
+	one.OnAppStart(func() error { started += "one"; return nil })
+	if err := two.Startup(); err != nil {
+		t.Fatal(err)
+	}
+	if started != "" || one.Ready() || !two.Ready() {
+		t.Error("hooks or readiness leaked between apps", started, one.Ready(), two.Ready())
+	}
+
+	one.Log.Info("hello from one")
+	two.Log.Info("hello from two")
+	two.Log.Debug("filtered")
+	for name, want := range map[string]string{"one": "hello from one", "two": "hello from two"} {
+		b, err := os.ReadFile(filepath.Join(dir, name+".log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 1 || !strings.Contains(lines[0], want) {
+			t.Errorf("%s.log: %q", name, b)
+		}
+	}
+}
+
+func TestDefaultAppDelegation(t *testing.T) {
+	n := len(Default().startupHooks)
+	OnAppStartHook(StartupHook{Name: "TestDefaultAppDelegation", Func: func() error { return nil }})
+	defer func() { defaultApp.startupHooks = defaultApp.startupHooks[:n] }()
+
+	if len(Default().startupHooks) != n+1 {
+		t.Error("OnAppStartHook did not register on the default app")
+	}
+}
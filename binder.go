@@ -27,12 +27,15 @@ package goboot
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
+	"net/url"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -63,6 +66,34 @@ type Binder struct {
 	// Unbind serializes a given value to one or more URL parameters of the given
 	// name.
 	Unbind func(output map[string]string, name string, val interface{})
+
+	// BindE is like Bind but reports why a value could not be bound, it is
+	// used instead of Bind when set. A missing parameter is not an error, it
+	// binds the zero value.
+	BindE func(params *Params, name string, typ reflect.Type) (reflect.Value, error)
+
+	// unbindWith is Unbind for the binders of nested values, it unbinds them
+	// with the binders of r.
+	unbindWith func(r *BinderRegistry, output map[string]string, name string, val interface{})
+}
+
+// BindError reports a parameter that could not be bound.
+type BindError struct {
+	Name  string       // parameter name
+	Type  reflect.Type // requested type
+	Value string       // rejected value, if any
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("bind %s as %s: %v", e.Name, e.Type, e.Err)
+	}
+	return fmt.Sprintf("bind %s=%q as %s: %v", e.Name, e.Value, e.Type, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
 }
 
 // ValueBinder is adapter for easily making one-key-value binders.
@@ -76,6 +107,45 @@ func ValueBinder(f func(value string, typ reflect.Type) reflect.Value) func(*Par
 	}
 }
 
+// ValueBinderE is ValueBinder for binders returning an error.
+func ValueBinderE(f func(value string, typ reflect.Type) (reflect.Value, error)) func(*Params, string, reflect.Type) (reflect.Value, error) {
+	return func(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
+		vals, ok := params.Values[name]
+		if !ok || len(vals) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		return f(vals[0], typ)
+	}
+}
+
+// lenient adapts an error returning bind function to Binder.Bind: errors
+// are logged and bind the zero value.
+func lenient(f func(*Params, string, reflect.Type) (reflect.Value, error)) func(*Params, string, reflect.Type) reflect.Value {
+	return func(params *Params, name string, typ reflect.Type) reflect.Value {
+		v, err := f(params, name, typ)
+		if err != nil {
+			params.logger().Warning(err)
+			return reflect.Zero(typ)
+		}
+		return v
+	}
+}
+
+// newBinder returns a Binder whose Bind is the lenient form of bindE.
+func newBinder(bindE func(*Params, string, reflect.Type) (reflect.Value, error), unbind func(map[string]string, string, interface{})) Binder {
+	return Binder{Bind: lenient(bindE), Unbind: unbind, BindE: bindE}
+}
+
+// newNestedBinder returns a Binder of nested values, unbind unbinds them with
+// the binders of its registry, the global ones for Unbind.
+func newNestedBinder(bindE func(*Params, string, reflect.Type) (reflect.Value, error), unbind func(*BinderRegistry, map[string]string, string, interface{})) Binder {
+	b := newBinder(bindE, func(output map[string]string, name string, val interface{}) {
+		unbind(nil, output, name, val)
+	})
+	b.unbindWith = unbind
+	return b
+}
+
 // Revel's default date and time constants
 const (
 	DefaultDateFormat     = "2006-01-02"
@@ -86,134 +156,265 @@ const (
 var (
 	// These are the lookups to find a Binder for any type of data.
 	// The most specific binder found will be used (Type before Kind)
+	// Writing them directly is only safe in init, use RegisterBinder and
+	// RegisterKindBinder anywhere else.
 	TypeBinders = make(map[reflect.Type]Binder)
 	KindBinders = make(map[reflect.Kind]Binder)
 
 	// Applications can add custom time formats to this array, and they will be
-	// automatically attempted when binding a time.Time.
-	TimeFormats = []string{}
+	// automatically attempted when binding a time.Time. Like the formats and
+	// the location below, it is only written directly in init, SetTimeFormats
+	// sets them safely afterwards.
+	TimeFormats = []string{DefaultDateTimeFormat, DefaultDateFormat}
 
-	DateFormat     string
-	DateTimeFormat string
+	DateFormat     = DefaultDateFormat
+	DateTimeFormat = DefaultDateTimeFormat
 
-	IntBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			intValue, err := strconv.ParseInt(val, 10, 64)
-			if err != nil {
-				Log.Warning(err)
-				return reflect.Zero(typ)
-			}
-			pValue := reflect.New(typ)
-			pValue.Elem().SetInt(intValue)
-			return pValue.Elem()
-		}),
-		Unbind: func(output map[string]string, key string, val interface{}) {
-			output[key] = fmt.Sprintf("%d", val)
-		},
-	}
-
-	UintBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			uintValue, err := strconv.ParseUint(val, 10, 64)
-			if err != nil {
-				Log.Warning(err)
-				return reflect.Zero(typ)
-			}
-			pValue := reflect.New(typ)
-			pValue.Elem().SetUint(uintValue)
-			return pValue.Elem()
-		}),
-		Unbind: func(output map[string]string, key string, val interface{}) {
-			output[key] = fmt.Sprintf("%d", val)
-		},
-	}
-
-	FloatBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			floatValue, err := strconv.ParseFloat(val, 64)
-			if err != nil {
-				Log.Warning(err)
-				return reflect.Zero(typ)
-			}
-			pValue := reflect.New(typ)
-			pValue.Elem().SetFloat(floatValue)
-			return pValue.Elem()
-		}),
-		Unbind: func(output map[string]string, key string, val interface{}) {
-			output[key] = fmt.Sprintf("%f", val)
-		},
-	}
+	// TimeLocation is the time zone of the times bound without one, and of
+	// the unbound times.
+	TimeLocation = time.UTC
 
-	StringBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			return reflect.ValueOf(val)
-		}),
-		Unbind: func(output map[string]string, name string, val interface{}) {
-			output[name] = val.(string)
-		},
-	}
+	IntBinder = newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		intValue, err := strconv.ParseInt(val, 10, typ.Bits())
+		if err != nil {
+			return reflect.Zero(typ), err
+		}
+		pValue := reflect.New(typ)
+		pValue.Elem().SetInt(intValue)
+		return pValue.Elem(), nil
+	}), func(output map[string]string, key string, val interface{}) {
+		output[key] = fmt.Sprintf("%d", val)
+	})
+
+	UintBinder = newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		uintValue, err := strconv.ParseUint(val, 10, typ.Bits())
+		if err != nil {
+			return reflect.Zero(typ), err
+		}
+		pValue := reflect.New(typ)
+		pValue.Elem().SetUint(uintValue)
+		return pValue.Elem(), nil
+	}), func(output map[string]string, key string, val interface{}) {
+		output[key] = fmt.Sprintf("%d", val)
+	})
+
+	FloatBinder = newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		floatValue, err := strconv.ParseFloat(val, typ.Bits())
+		if err != nil {
+			return reflect.Zero(typ), err
+		}
+		pValue := reflect.New(typ)
+		pValue.Elem().SetFloat(floatValue)
+		return pValue.Elem(), nil
+	}), func(output map[string]string, key string, val interface{}) {
+		v := reflect.ValueOf(val)
+		output[key] = strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	})
+
+	StringBinder = newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		return reflect.ValueOf(val).Convert(typ), nil
+	}), func(output map[string]string, name string, val interface{}) {
+		output[name] = reflect.ValueOf(val).String()
+	})
 
 	// Booleans support a various value formats,
 	// refer `revel.Atob` method.
-	BoolBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			return reflect.ValueOf(Atob(val))
-		}),
-		Unbind: func(output map[string]string, name string, val interface{}) {
-			output[name] = fmt.Sprintf("%t", val)
-		},
-	}
-
-	PointerBinder = Binder{
-		Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
-			v := Bind(params, name, typ.Elem())
-			if v.CanAddr() {
-				return v.Addr()
-			}
+	BoolBinder = newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		return reflect.ValueOf(Atob(val)).Convert(typ), nil
+	}), func(output map[string]string, name string, val interface{}) {
+		output[name] = fmt.Sprintf("%t", val)
+	})
+
+	PointerBinder = newNestedBinder(func(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
+		v, err := BindE(params, name, typ.Elem())
+		if err != nil {
+			return reflect.Zero(typ), err
+		}
+		if v.CanAddr() {
+			return v.Addr(), nil
+		}
+		p := reflect.New(typ.Elem())
+		p.Elem().Set(v)
+		return p, nil
+	}, func(r *BinderRegistry, output map[string]string, name string, val interface{}) {
+		if v := reflect.ValueOf(val); !v.IsNil() {
+			r.Unbind(output, name, v.Elem().Interface())
+		}
+	})
+
+	// Times are parsed with TimeFormats in TimeLocation, then as RFC 3339,
+	// then as a Unix time in seconds with an optional fraction. They are
+	// unbound with DateTimeFormat, or DateFormat at midnight, unless the
+	// format drops a part of the time, e.g. seconds, then as RFC 3339.
+	TimeBinder = newTimeBinder(globalTimeFormats)
+
+	// Durations are Go durations, e.g. 1h30m, or a number of seconds.
+	DurationBinder = newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return reflect.ValueOf(time.Duration(n) * time.Second), nil
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return reflect.Zero(typ), err
+		}
+		return reflect.ValueOf(d), nil
+	}), func(output map[string]string, name string, val interface{}) {
+		output[name] = val.(time.Duration).String()
+	})
+
+	MapBinder = newNestedBinder(bindMap, unbindMap)
+)
 
-			return v
-		},
-		Unbind: func(output map[string]string, name string, val interface{}) {
-			Unbind(output, name, reflect.ValueOf(val).Elem().Interface())
-		},
+// parseUnixTime parses a number of seconds since the Unix epoch, with an
+// optional fraction of up to nanoseconds, e.g. 1700000000 or 1700000000.25.
+func parseUnixTime(val string) (time.Time, bool) {
+	sec, frac := val, ""
+	if i := strings.IndexByte(val, '.'); i >= 0 {
+		sec, frac = val[:i], val[i+1:]
+		if frac == "" || len(frac) > 9 || strings.TrimLeft(frac, "0123456789") != "" {
+			return time.Time{}, false
+		}
+	}
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var nsec int64
+	if frac != "" {
+		nsec, _ = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if strings.HasPrefix(sec, "-") {
+			nsec = -nsec
+		}
 	}
+	return time.Unix(s, nsec), true
+}
 
-	TimeBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			for _, f := range TimeFormats {
-				if r, err := time.Parse(f, val); err == nil {
-					return reflect.ValueOf(r)
-				}
-			}
-			return reflect.Zero(typ)
-		}),
-		Unbind: func(output map[string]string, name string, val interface{}) {
-			var (
-				t       = val.(time.Time)
-				format  = DateTimeFormat
-				h, m, s = t.Clock()
-			)
-			if h == 0 && m == 0 && s == 0 {
-				format = DateFormat
+// timeFormats are the formats and the location of a time binder.
+type timeFormats struct {
+	formats        []string
+	date, dateTime string
+	loc            *time.Location
+}
+
+// globalTimeFormats returns TimeFormats, DateFormat, DateTimeFormat and
+// TimeLocation.
+func globalTimeFormats() timeFormats {
+	bindersMu.RLock()
+	defer bindersMu.RUnlock()
+	return timeFormats{TimeFormats, DateFormat, DateTimeFormat, TimeLocation}
+}
+
+// newTimeBinder returns a binder of time.Time with the formats returned by
+// get, see TimeBinder.
+func newTimeBinder(get func() timeFormats) Binder {
+	return newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		tf := get()
+		for _, f := range tf.formats {
+			if r, err := time.ParseInLocation(f, val, tf.loc); err == nil {
+				return reflect.ValueOf(r), nil
 			}
-			output[name] = t.Format(format)
-		},
+		}
+		if r, err := time.Parse(time.RFC3339Nano, val); err == nil {
+			return reflect.ValueOf(r), nil
+		}
+		if r, ok := parseUnixTime(val); ok {
+			return reflect.ValueOf(r.In(tf.loc)), nil
+		}
+		return reflect.Zero(typ), errors.New("no time format matches")
+	}), func(output map[string]string, name string, val interface{}) {
+		var (
+			tf      = get()
+			t       = val.(time.Time).In(tf.loc)
+			format  = tf.dateTime
+			h, m, s = t.Clock()
+		)
+		if h == 0 && m == 0 && s == 0 {
+			format = tf.date
+		}
+		v := t.Format(format)
+		if p, err := time.ParseInLocation(format, v, tf.loc); err != nil || !p.Equal(t) {
+			v = t.Format(time.RFC3339Nano)
+		}
+		output[name] = v
+	})
+}
+
+// SetTimeFormats sets DateFormat, DateTimeFormat and TimeLocation from the
+// format.date, format.datetime and format.timezone keys of c. TimeFormats
+// becomes the date time format, the date format, the format.time.* formats
+// sorted by key, then the formats added by the application. The default App
+// calls it on Startup, other Apps bind times with their own formats, see
+// App.Startup.
+//
+// e.g.
+//
+//	format.date=02/01/2006
+//	format.datetime=02/01/2006 15:04
+//	format.time.iso=2006-01-02T15:04:05
+//	format.timezone=Europe/Paris
+func SetTimeFormats(c *ConfigContext) error {
+	tf, err := loadTimeFormats(c)
+	if err != nil {
+		return err
 	}
+	bindersMu.Lock()
+	defer bindersMu.Unlock()
+	TimeFormats, DateFormat, DateTimeFormat, TimeLocation = tf.formats, tf.date, tf.dateTime, tf.loc
+	return nil
+}
 
-	MapBinder = Binder{
-		Bind:   bindMap,
-		Unbind: unbindMap,
+// loadTimeFormats reads the time formats of c, see SetTimeFormats.
+func loadTimeFormats(c *ConfigContext) (timeFormats, error) {
+	loc := time.UTC
+	if name := c.MustString(IniFormatTimeZone); name != "" {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return timeFormats{}, fmt.Errorf("%s: %v", IniFormatTimeZone, err)
+		}
 	}
-)
+
+	formats := []string{c.MustString(IniFormatDateTime, DefaultDateTimeFormat), c.MustString(IniFormatDate, DefaultDateFormat)}
+	extra := c.MustStringMap(IniFormatTimePrefix)
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		formats = append(formats, extra[k])
+	}
+	global := globalTimeFormats()
+	for _, f := range global.formats {
+		if f != global.dateTime && f != global.date && !containsString(formats, f) {
+			formats = append(formats, f)
+		}
+	}
+	return timeFormats{formats: formats, date: formats[1], dateTime: formats[0], loc: loc}, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
 
 // Used to keep track of the index for individual keyvalues.
 type sliceValue struct {
@@ -225,15 +426,16 @@ type sliceValue struct {
 // elements, and then sets them to their appropriate location in the slice.
 // If elements are provided without an explicit index, they are added (in
 // unspecified order) to the end of the slice.
-func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
+func bindSlice(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
 	// Collect an array of slice elements with their indexes (and the max index).
 	maxIndex := -1
 	numNoIndex := 0
 	sliceValues := []sliceValue{}
+	var firstErr error
 
 	// Factor out the common slice logic (between form values and files).
 	processElement := func(key string, vals []string, files []*multipart.FileHeader) {
-		if !strings.HasPrefix(key, name+"[") {
+		if !strings.HasPrefix(key, name+"[") || firstErr != nil {
 			return
 		}
 
@@ -250,9 +452,14 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 			if index > maxIndex {
 				maxIndex = index
 			}
+			v, err := BindE(params, key[:subKeyIndex], typ.Elem())
+			if err != nil {
+				firstErr = err
+				return
+			}
 			sliceValues = append(sliceValues, sliceValue{
 				index: index,
-				value: Bind(params, key[:subKeyIndex], typ.Elem()),
+				value: v,
 			})
 			return
 		}
@@ -261,16 +468,26 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 		numNoIndex += len(vals) + len(files)
 		for _, val := range vals {
 			// Unindexed values can only be direct-bound.
+			v, err := BindE(&Params{Values: map[string][]string{key: {val}}, parent: params}, key, typ.Elem())
+			if err != nil {
+				firstErr = err
+				return
+			}
 			sliceValues = append(sliceValues, sliceValue{
 				index: -1,
-				value: BindValue(val, typ.Elem()),
+				value: v,
 			})
 		}
 
 		for _, fileHeader := range files {
+			v, err := BindE(&Params{Files: map[string][]*multipart.FileHeader{key: {fileHeader}}, parent: params}, key, typ.Elem())
+			if err != nil {
+				firstErr = err
+				return
+			}
 			sliceValues = append(sliceValues, sliceValue{
 				index: -1,
-				value: BindFile(fileHeader, typ.Elem()),
+				value: v,
 			})
 		}
 	}
@@ -281,6 +498,9 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 	for key, fileHeaders := range params.Files {
 		processElement(key, nil, fileHeaders)
 	}
+	if firstErr != nil {
+		return reflect.Zero(typ), firstErr
+	}
 
 	resultArray := reflect.MakeSlice(typ, maxIndex+1, maxIndex+1+numNoIndex)
 	for _, sv := range sliceValues {
@@ -291,7 +511,7 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 		}
 	}
 
-	return resultArray
+	return resultArray, nil
 }
 
 // Break on dots and brackets.
@@ -304,138 +524,256 @@ func nextKey(key string) string {
 	return key[:fieldLen]
 }
 
-func unbindSlice(output map[string]string, name string, val interface{}) {
+func unbindSlice(r *BinderRegistry, output map[string]string, name string, val interface{}) {
 	v := reflect.ValueOf(val)
 	for i := 0; i < v.Len(); i++ {
-		Unbind(output, fmt.Sprintf("%s[%d]", name, i), v.Index(i).Interface())
+		r.Unbind(output, fmt.Sprintf("%s[%d]", name, i), v.Index(i).Interface())
 	}
 }
 
-func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
+func bindStruct(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
 	resultPointer := reflect.New(typ)
 	result := resultPointer.Elem()
 	if params.JSON != nil {
-		// Try to inject the response as a json into the created result
+		// Try to inject the response as a json into the created result, the
+		// params then override the fields they name.
 		if err := json.Unmarshal(params.JSON, resultPointer.Interface()); err != nil {
-			Log.Warning("W: bindStruct: Unable to unmarshal request:", name, err)
+			return result, err
 		}
-		return result
+		params = &Params{Values: params.Values, Files: params.Files, parent: params}
+	}
+	if errs := bindFields(params, name, result); len(errs) > 0 {
+		return reflect.Zero(typ), errs[0]
 	}
-	fieldValues := make(map[string]reflect.Value)
-	for key := range params.Values {
-		if !strings.HasPrefix(key, name+".") {
+	return result, nil
+}
+
+// bindFields binds the fields of the struct v having a param named
+// prefix.field, or field when prefix is empty, see structFields. Fields
+// without a param keep their value. It returns the errors of BindE.
+func bindFields(params *Params, prefix string, v reflect.Value) []error {
+	var errs []error
+	for _, f := range structFields(v.Type()) {
+		key := joinPath(prefix, f.name)
+		if !params.has(key) {
+			continue
+		}
+		boundVal, err := BindE(params, key, f.typ)
+		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
+		fieldByIndex(v, f.index).Set(boundVal)
+	}
+	return errs
+}
 
-		// Get the name of the struct property.
-		// Strip off the prefix. e.g. foo.bar.baz => bar.baz
-		suffix := key[len(name)+1:]
-		fieldName := nextKey(suffix)
-		fieldLen := len(fieldName)
+func unbindStruct(r *BinderRegistry, output map[string]string, name string, iface interface{}) {
+	val := reflect.ValueOf(iface)
+	for _, f := range structFields(val.Type()) {
+		fieldValue, err := val.FieldByIndexErr(f.index)
+		if err != nil || fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+			continue
+		}
+		if f.omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+		r.Unbind(output, joinPath(name, f.name), fieldValue.Interface())
+	}
+}
 
-		if _, ok := fieldValues[fieldName]; !ok {
-			// Time to bind this field.  Get it and make sure we can set it.
-			fieldValue := result.FieldByName(fieldName)
-			if !fieldValue.IsValid() {
-				Log.Warning("W: bindStruct: Field not found:", fieldName)
-				continue
-			}
-			if !fieldValue.CanSet() {
-				Log.Warning("W: bindStruct: Field not settable:", fieldName)
-				continue
-			}
-			boundVal := Bind(params, key[:len(name)+1+fieldLen], fieldValue.Type())
-			fieldValue.Set(boundVal)
-			fieldValues[fieldName] = boundVal
+// structField is a field bound by bindStruct.
+type structField struct {
+	name      string
+	index     []int // for reflect.Value.FieldByIndex
+	typ       reflect.Type
+	omitEmpty bool
+	tagged    bool // named by a form or json tag
+}
+
+// structFields returns the fields of the struct type typ bound to params:
+// the exported fields not tagged "-", named by fieldName. The fields of
+// embedded structs without a name tag are promoted, a shallower field hiding
+// the deeper ones of the same name. As in encoding/json, of several fields of
+// the same name at the shallowest depth the only tagged one is bound, they
+// are ambiguous and none is bound otherwise.
+func structFields(typ reflect.Type) []structField {
+	fields := appendFields(nil, typ, nil)
+	byName := make(map[string][]structField, len(fields))
+	for _, f := range fields {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	visible := fields[:0]
+	for _, f := range fields {
+		if dominant, ok := dominantField(byName[f.name]); ok && sameIndex(dominant.index, f.index) {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+// dominantField returns the field bound among fields of the same name, see
+// structFields.
+func dominantField(fields []structField) (structField, bool) {
+	depth := len(fields[0].index)
+	for _, f := range fields {
+		if len(f.index) < depth {
+			depth = len(f.index)
+		}
+	}
+	var dominant []structField
+	var tagged []structField
+	for _, f := range fields {
+		if len(f.index) != depth {
+			continue
 		}
+		dominant = append(dominant, f)
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(dominant) == 1 {
+		return dominant[0], true
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
 	}
+	return structField{}, false
+}
 
-	return result
+func sameIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
-func unbindStruct(output map[string]string, name string, iface interface{}) {
-	val := reflect.ValueOf(iface)
-	typ := val.Type()
-	for i := 0; i < val.NumField(); i++ {
-		structField := typ.Field(i)
-		fieldValue := val.Field(i)
+func appendFields(fields []structField, typ reflect.Type, index []int) []structField {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		name, omitEmpty := fieldTag(sf)
+		if name == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
 
+		if sf.Anonymous && name == "" {
+			t := sf.Type
+			if t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			// The fields of an unexported embedded struct are promoted too,
+			// unless it is a pointer which could not be allocated.
+			if t.Kind() == reflect.Struct && (sf.PkgPath == "" || sf.Type.Kind() != reflect.Ptr) {
+				fields = appendFields(fields, t, fieldIndex)
+				continue
+			}
+		}
 		// PkgPath is specified to be empty exactly for exported fields.
-		if structField.PkgPath == "" {
-			Unbind(output, fmt.Sprintf("%s.%s", name, structField.Name), fieldValue.Interface())
+		if sf.PkgPath != "" {
+			continue
 		}
+		fields = append(fields, structField{name: fieldName(sf), index: fieldIndex, typ: sf.Type, omitEmpty: omitEmpty, tagged: name != ""})
 	}
+	return fields
 }
 
-// Helper that returns an upload of the given name, or nil.
-func getMultipartFile(params *Params, name string) multipart.File {
+// fieldByIndex is reflect.Value.FieldByIndex allocating the nil embedded
+// struct pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// Helper that returns an upload of the given name, or nil when there is
+// none.
+func getMultipartFile(params *Params, name string) (multipart.File, error) {
+	var err error
 	for _, fileHeader := range params.Files[name] {
-		file, err := fileHeader.Open()
-		if err == nil {
-			return file
+		var file multipart.File
+		if file, err = fileHeader.Open(); err == nil {
+			return file, nil
 		}
-		Log.Warning("Failed to open uploaded file", name, ":", err)
 	}
-	return nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %v", err)
+	}
+	return nil, nil
 }
 
-func bindFile(params *Params, name string, typ reflect.Type) reflect.Value {
-	reader := getMultipartFile(params, name)
+func bindFile(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
+	reader, err := getMultipartFile(params, name)
 	if reader == nil {
-		return reflect.Zero(typ)
+		return reflect.Zero(typ), err
 	}
 
 	// If it's already stored in a temp file, just return that.
 	if osFile, ok := reader.(*os.File); ok {
-		return reflect.ValueOf(osFile)
+		params.root().files = append(params.root().files, osFile)
+		return reflect.ValueOf(osFile), nil
 	}
+	defer reader.Close()
 
 	// Otherwise, have to store it.
-	tmpFile, err := ioutil.TempFile("", "revel-upload")
+	tmpFile, err := ioutil.TempFile("", UploadTempPattern)
 	if err != nil {
-		Log.Warning("Failed to create a temp file to store upload:", err)
-		return reflect.Zero(typ)
+		return reflect.Zero(typ), fmt.Errorf("failed to create a temp file to store upload: %v", err)
 	}
 
-	// Register it to be deleted after the request is done.
-	params.tmpFiles = append(params.tmpFiles, tmpFile)
+	// Register it to be deleted after the request is done, see Params.Close.
+	params.root().tmpFiles = append(params.root().tmpFiles, tmpFile)
 
 	_, err = io.Copy(tmpFile, reader)
 	if err != nil {
-		Log.Warning("Failed to copy upload to temp file:", err)
-		return reflect.Zero(typ)
+		return reflect.Zero(typ), fmt.Errorf("failed to copy upload to temp file: %v", err)
 	}
 
 	_, err = tmpFile.Seek(0, 0)
 	if err != nil {
-		Log.Warning("Failed to seek to beginning of temp file:", err)
-		return reflect.Zero(typ)
+		return reflect.Zero(typ), fmt.Errorf("failed to seek to beginning of temp file: %v", err)
 	}
 
-	return reflect.ValueOf(tmpFile)
+	return reflect.ValueOf(tmpFile), nil
 }
 
-func bindByteArray(params *Params, name string, typ reflect.Type) reflect.Value {
-	if reader := getMultipartFile(params, name); reader != nil {
-		b, err := ioutil.ReadAll(reader)
-		if err == nil {
-			return reflect.ValueOf(b)
-		}
-		Log.Warning("Error reading uploaded file contents:", err)
+func bindByteArray(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
+	reader, err := getMultipartFile(params, name)
+	if reader == nil {
+		return reflect.Zero(typ), err
 	}
-	return reflect.Zero(typ)
+	b, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return reflect.Zero(typ), fmt.Errorf("error reading uploaded file contents: %v", err)
+	}
+	return reflect.ValueOf(b), nil
 }
 
-func bindReadSeeker(params *Params, name string, typ reflect.Type) reflect.Value {
-	if reader := getMultipartFile(params, name); reader != nil {
-		return reflect.ValueOf(reader.(io.ReadSeeker))
+func bindReadSeeker(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
+	reader, err := getMultipartFile(params, name)
+	if reader == nil {
+		return reflect.Zero(typ), err
 	}
-	return reflect.Zero(typ)
+	params.root().files = append(params.root().files, reader)
+	return reflect.ValueOf(reader.(io.ReadSeeker)), nil
 }
 
 // bindMap converts parameters using map syntax into the corresponding map. e.g.:
 //   params["a[5]"]=foo, name="a", typ=map[int]string => map[int]string{5: "foo"}
-func bindMap(params *Params, name string, typ reflect.Type) reflect.Value {
+func bindMap(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
 	var (
 		keyType   = typ.Key()
 		valueType = typ.Elem()
@@ -446,9 +784,9 @@ func bindMap(params *Params, name string, typ reflect.Type) reflect.Value {
 	if params.JSON != nil {
 		// Try to inject the response as a json into the created result
 		if err := json.Unmarshal(params.JSON, resultPtr.Interface()); err != nil {
-			Log.Warning("W: bindMap: Unable to unmarshal request:", name, err)
+			return result, err
 		}
-		return result
+		return result, nil
 	}
 
 	for paramName, values := range params.Values {
@@ -457,15 +795,23 @@ func bindMap(params *Params, name string, typ reflect.Type) reflect.Value {
 		}
 
 		key := paramName[len(name)+1 : len(paramName)-1]
-		result.SetMapIndex(BindValue(key, keyType), BindValue(values[0], valueType))
+		k, err := BindE(&Params{Values: map[string][]string{paramName: {key}}, parent: params}, paramName, keyType)
+		if err != nil {
+			return result, err
+		}
+		v, err := BindE(&Params{Values: map[string][]string{paramName: {values[0]}}, parent: params}, paramName, valueType)
+		if err != nil {
+			return result, err
+		}
+		result.SetMapIndex(k, v)
 	}
-	return result
+	return result, nil
 }
 
-func unbindMap(output map[string]string, name string, iface interface{}) {
+func unbindMap(r *BinderRegistry, output map[string]string, name string, iface interface{}) {
 	mapValue := reflect.ValueOf(iface)
 	for _, key := range mapValue.MapKeys() {
-		Unbind(output, name+"["+fmt.Sprintf("%v", key.Interface())+"]",
+		r.Unbind(output, name+"["+fmt.Sprintf("%v", key.Interface())+"]",
 			mapValue.MapIndex(key).Interface())
 	}
 }
@@ -474,40 +820,76 @@ func unbindMap(output map[string]string, name string, iface interface{}) {
 // from one or more values from Params.
 // Returns the zero value of the type upon any sort of failure.
 func Bind(params *Params, name string, typ reflect.Type) reflect.Value {
-	if binder, found := binderForType(typ); found {
-		return binder.Bind(params, name, typ)
+	v, err := BindE(params, name, typ)
+	if err != nil {
+		params.logger().Warning(err)
+		return reflect.Zero(typ)
+	}
+	return v
+}
+
+// BindE is like Bind but returns a *BindError when the parameter can not be
+// bound. A missing parameter binds the zero value without an error.
+func BindE(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
+	var binders *BinderRegistry
+	if params != nil {
+		binders = params.root().Binders
+	}
+	binder, found := binders.binderFor(typ, false)
+	if !found {
+		return reflect.Zero(typ), &BindError{Name: name, Type: typ, Err: errors.New("no binder for type")}
+	}
+	if binder.BindE == nil {
+		return binder.Bind(params, name, typ), nil
+	}
+
+	v, err := binder.BindE(params, name, typ)
+	if err == nil {
+		return v, nil
 	}
-	return reflect.Zero(typ)
+	if be, ok := err.(*BindError); ok {
+		return reflect.Zero(typ), be
+	}
+	var value string
+	if params != nil {
+		value = params.Values.Get(name)
+	}
+	return reflect.Zero(typ), &BindError{Name: name, Type: typ, Value: value, Err: err}
 }
 
 func BindValue(val string, typ reflect.Type) reflect.Value {
 	return Bind(&Params{Values: map[string][]string{"": {val}}}, "", typ)
 }
 
+// BindValueE is like BindValue but returns the error of BindE.
+func BindValueE(val string, typ reflect.Type) (reflect.Value, error) {
+	return BindE(&Params{Values: map[string][]string{"": {val}}}, "", typ)
+}
+
 func BindFile(fileHeader *multipart.FileHeader, typ reflect.Type) reflect.Value {
 	return Bind(&Params{Files: map[string][]*multipart.FileHeader{"": {fileHeader}}}, "", typ)
 }
 
+// Unbind serializes val to one or more URL parameters of the given name with
+// the global binders, see BinderRegistry.Unbind.
 func Unbind(output map[string]string, name string, val interface{}) {
-	if binder, found := binderForType(reflect.TypeOf(val)); found {
-		if binder.Unbind != nil {
-			binder.Unbind(output, name, val)
-		} else {
-			Log.Errorf("revel/binder: can not unbind %s=%s", name, val)
-		}
-	}
+	(*BinderRegistry)(nil).Unbind(output, name, val)
 }
 
-func binderForType(typ reflect.Type) (Binder, bool) {
-	binder, ok := TypeBinders[typ]
-	if !ok {
-		binder, ok = KindBinders[typ.Kind()]
-		if !ok {
-			Log.Warning("revel/binder: no binder for type:", typ)
-			return Binder{}, false
-		}
-	}
-	return binder, true
+// EncodeQuery encodes the fields of the struct v, or the entries of the map
+// v, with Unbind, so that Params.BindStruct and Bind decode them back: nested
+// structs are dotted names, slices and maps indexed names, e.g.
+//
+//	EncodeQuery(struct {
+//		Tags []string `form:"tags"`
+//		Page int      `form:"page,omitempty"`
+//	}{Tags: []string{"a", "b"}}) // tags[0]=a&tags[1]=b
+//
+// url.Values are returned as is. A nil v, or another kind, gives empty Values.
+// Values bound with other binders than the global ones are encoded with
+// BinderRegistry.EncodeQuery.
+func EncodeQuery(v interface{}) url.Values {
+	return (*BinderRegistry)(nil).EncodeQuery(v)
 }
 
 // Sadly, the binder lookups can not be declared initialized -- that results in
@@ -530,24 +912,19 @@ func init() {
 
 	KindBinders[reflect.String] = StringBinder
 	KindBinders[reflect.Bool] = BoolBinder
-	KindBinders[reflect.Slice] = Binder{bindSlice, unbindSlice}
-	KindBinders[reflect.Struct] = Binder{bindStruct, unbindStruct}
+	KindBinders[reflect.Slice] = newNestedBinder(bindSlice, unbindSlice)
+	KindBinders[reflect.Struct] = newNestedBinder(bindStruct, unbindStruct)
 	KindBinders[reflect.Ptr] = PointerBinder
 	KindBinders[reflect.Map] = MapBinder
 
 	TypeBinders[reflect.TypeOf(time.Time{})] = TimeBinder
+	TypeBinders[reflect.TypeOf(time.Duration(0))] = DurationBinder
 
 	// Uploads
-	TypeBinders[reflect.TypeOf(&os.File{})] = Binder{bindFile, nil}
-	TypeBinders[reflect.TypeOf([]byte{})] = Binder{bindByteArray, nil}
-	TypeBinders[reflect.TypeOf((*io.Reader)(nil)).Elem()] = Binder{bindReadSeeker, nil}
-	TypeBinders[reflect.TypeOf((*io.ReadSeeker)(nil)).Elem()] = Binder{bindReadSeeker, nil}
-
-	// OnAppStart(func() {
-	// 		DateTimeFormat = Config.StringDefault("format.datetime", DefaultDateTimeFormat)
-	// 		DateFormat = Config.StringDefault("format.date", DefaultDateFormat)
-	// 		TimeFormats = append(TimeFormats, DateTimeFormat, DateFormat)
-	// 	})
+	TypeBinders[reflect.TypeOf(&os.File{})] = newBinder(bindFile, nil)
+	TypeBinders[reflect.TypeOf([]byte{})] = newBinder(bindByteArray, nil)
+	TypeBinders[reflect.TypeOf((*io.Reader)(nil)).Elem()] = newBinder(bindReadSeeker, nil)
+	TypeBinders[reflect.TypeOf((*io.ReadSeeker)(nil)).Elem()] = newBinder(bindReadSeeker, nil)
 }
 
 // ToBool method converts/assert value into true or false. Default is true.
@@ -0,0 +1,407 @@
+package goboot
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	logging "github.com/op/go-logging"
+)
+
+type testCelsius float64
+
+type testUser struct {
+	Name string
+	Age  int
+}
+
+func TestBindE(t *testing.T) {
+	withTestLog(t)
+	p := &Params{Values: url.Values{
+		"n":         {"42"},
+		"bad":       {"4x"},
+		"small":     {"300"},
+		"list[0]":   {"1"},
+		"list[1]":   {"x"},
+		"user.Name": {"rob"},
+		"user.Age":  {"old"},
+	}}
+
+	v, err := BindE(p, "n", reflect.TypeOf(0))
+	if err != nil || v.Int() != 42 {
+		t.Fatalf("n = %v, %v", v, err)
+	}
+	if v, err = BindE(p, "missing", reflect.TypeOf(0)); err != nil || v.Int() != 0 {
+		t.Fatalf("missing = %v, %v", v, err)
+	}
+
+	for _, c := range []struct {
+		name string
+		typ  reflect.Type
+		bad  string
+	}{
+		{"bad", reflect.TypeOf(0), "bad"},
+		{"small", reflect.TypeOf(int8(0)), "small"},
+		{"list", reflect.TypeOf([]int{}), "list[1]"},
+		{"user", reflect.TypeOf(testUser{}), "user.Age"},
+		{"n", reflect.TypeOf(make(chan int)), "n"},
+	} {
+		_, err := BindE(p, c.name, c.typ)
+		var be *BindError
+		if !errors.As(err, &be) || be.Name != c.bad {
+			t.Errorf("%s: err = %v", c.name, err)
+		}
+		if v := Bind(p, c.name, c.typ); !v.IsZero() {
+			t.Errorf("%s: lenient Bind = %v", c.name, v)
+		}
+	}
+
+	var n int
+	if err := p.BindE(&n, "bad"); err == nil || n != 0 {
+		t.Errorf("Params.BindE = %v, %d", err, n)
+	}
+	if err := p.BindE(&n, "n"); err != nil || n != 42 {
+		t.Errorf("Params.BindE = %v, %d", err, n)
+	}
+}
+
+func TestBindLogsToApp(t *testing.T) {
+	prev := Log
+	Log = nil
+	t.Cleanup(func() { Log = prev })
+
+	a := newTestApp(t)
+	mem := logging.NewMemoryBackend(4)
+	a.SetLogBackend(logging.AddModuleLevel(mem))
+	p := &Params{}
+	if err := a.ParseParams(p, httptest.NewRequest("GET", "/?id=abc", nil)); err != nil {
+		t.Fatal(err)
+	}
+	var id int
+	p.Bind(&id, "id")
+	if id != 0 || mem.Head() == nil {
+		t.Errorf("id %d, bind warning not logged to the App", id)
+	}
+
+	// Without an App nor Log the warnings are discarded.
+	(&Params{Values: url.Values{"id": {"abc"}}}).Bind(&id, "id")
+}
+
+func TestCustomBinderE(t *testing.T) {
+	withTestLog(t)
+	typ := reflect.TypeOf(testCelsius(0))
+	errCold := errors.New("below absolute zero")
+	RegisterBinder(typ, Binder{
+		BindE: ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+			v, err := BindValueE(val, reflect.TypeOf(0.0))
+			if err != nil {
+				return v, err
+			}
+			if v.Float() < -273.15 {
+				return reflect.Zero(typ), errCold
+			}
+			return v.Convert(typ), nil
+		}),
+	})
+	defer func() {
+		bindersMu.Lock()
+		delete(TypeBinders, typ)
+		bindersMu.Unlock()
+	}()
+
+	p := &Params{Values: url.Values{"ok": {"21.5"}, "cold": {"-300"}}}
+	if v, err := BindE(p, "ok", typ); err != nil || v.Float() != 21.5 {
+		t.Errorf("ok = %v, %v", v, err)
+	}
+	_, err := BindE(p, "cold", typ)
+	var be *BindError
+	if !errors.As(err, &be) || be.Value != "-300" || !errors.Is(err, errCold) {
+		t.Errorf("cold: err = %v", err)
+	}
+
+	var s struct{ Temp testCelsius }
+	p = &Params{Values: url.Values{"Temp": {"-300"}}}
+	errs, ok := p.BindStruct(&s).(ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Field != "Temp" || errs[0].Rule != "type" {
+		t.Errorf("BindStruct = %v", errs)
+	}
+}
+
+type testBase struct {
+	ID      int    `form:"id"`
+	Created string `json:"created,omitempty"`
+}
+
+// Meta is exported as encoding/json can not allocate embedded pointers to
+// unexported types.
+type Meta struct {
+	Note string `json:"note"`
+}
+
+type testAccount struct {
+	testBase
+	*Meta
+	Name     string `form:"name" json:"full_name"`
+	Email    string `json:"email,omitempty"`
+	Secret   string `form:"-"`
+	Created  string `form:"created,omitempty"`
+	Internal int    `json:"-"`
+}
+
+func TestBindStructTags(t *testing.T) {
+	withTestLog(t)
+	p := &Params{Values: url.Values{
+		"acct.id":        {"7"},
+		"acct.note":      {"hi"},
+		"acct.name":      {"rob"},
+		"acct.full_name": {"ignored"},
+		"acct.Secret":    {"ignored"},
+		"acct.created":   {"today"},
+		"acct.Internal":  {"1"},
+	}}
+	var a testAccount
+	p.Bind(&a, "acct")
+	if a.ID != 7 || a.Meta == nil || a.Note != "hi" || a.Name != "rob" || a.Secret != "" || a.Created != "today" || a.testBase.Created != "" || a.Internal != 0 {
+		t.Errorf("bound %+v, meta %+v", a, a.Meta)
+	}
+
+	out := map[string]string{}
+	Unbind(out, "acct", testAccount{Name: "rob", testBase: testBase{ID: 7}})
+	want := map[string]string{"acct.id": "7", "acct.name": "rob"}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("unbind = %v", out)
+	}
+}
+
+type testLeft struct {
+	Code string
+	Zone string `form:"zone"`
+}
+
+type testRight struct {
+	Code string
+	Zone string
+}
+
+func TestBindStructAmbiguous(t *testing.T) {
+	withTestLog(t)
+	var v struct {
+		testLeft
+		testRight
+	}
+	p := &Params{Values: url.Values{"Code": {"c"}, "zone": {"z"}}}
+	if err := p.BindStruct(&v); err != nil {
+		t.Fatal(err)
+	}
+	// Code is ambiguous, the tagged Zone dominates as in encoding/json.
+	if v.testLeft.Code != "" || v.testRight.Code != "" || v.testLeft.Zone != "z" || v.testRight.Zone != "" {
+		t.Errorf("bound %+v", v)
+	}
+}
+
+func TestBindStructJSONAndParams(t *testing.T) {
+	withTestLog(t)
+	p := &Params{
+		JSON:   []byte(`{"id":1,"full_name":"body","email":"a@example.com","note":"n"}`),
+		Values: url.Values{"id": {"2"}},
+	}
+	var a testAccount
+	if err := p.BindStruct(&a); err != nil {
+		t.Fatal(err)
+	}
+	// encoding/json reads the json tags, the params the form tags and win.
+	if a.ID != 2 || a.Name != "body" || a.Email != "a@example.com" || a.Meta == nil || a.Note != "n" {
+		t.Errorf("bound %+v", a)
+	}
+}
+
+func TestTimeBinder(t *testing.T) {
+	withTestLog(t)
+	formats, date, dateTime, loc := TimeFormats, DateFormat, DateTimeFormat, TimeLocation
+	defer func() {
+		TimeFormats, DateFormat, DateTimeFormat, TimeLocation = formats, date, dateTime, loc
+	}()
+	typ := reflect.TypeOf(time.Time{})
+	bind := func(val string) (time.Time, error) {
+		v, err := BindValueE(val, typ)
+		return v.Interface().(time.Time), err
+	}
+
+	for val, want := range map[string]time.Time{
+		"2024-03-01":                time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2024-03-01 10:30":          time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		"2024-03-01T10:30:00+02:00": time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC),
+		"1709289000":                time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		"1709289000.5":              time.Date(2024, 3, 1, 10, 30, 0, 5e8, time.UTC),
+	} {
+		if got, err := bind(val); err != nil || !got.Equal(want) {
+			t.Errorf("%s = %v, %v", val, got, err)
+		}
+	}
+	for _, val := range []string{"01/03/2024", "1709289000.", "1e9"} {
+		if _, err := bind(val); err == nil {
+			t.Errorf("%s bound", val)
+		}
+	}
+
+	TimeFormats = append(TimeFormats, "Jan 2 2006")
+	c := NewConfigWithoutFile("test")
+	c.RunModeSection.NewKey(IniFormatDate, "02/01/2006")
+	c.RunModeSection.NewKey(IniFormatTimePrefix+"iso", "2006-01-02T15:04:05")
+	c.RunModeSection.NewKey(IniFormatTimeZone, "Asia/Tokyo")
+	if err := SetTimeFormats(c); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{DefaultDateTimeFormat, "02/01/2006", "2006-01-02T15:04:05", "Jan 2 2006"}
+	if !reflect.DeepEqual(TimeFormats, want) || DateFormat != "02/01/2006" {
+		t.Fatalf("formats %q", TimeFormats)
+	}
+	tokyo := TimeLocation
+	for val, want := range map[string]time.Time{
+		"01/03/2024":          time.Date(2024, 3, 1, 0, 0, 0, 0, tokyo),
+		"2024-03-01T10:30:00": time.Date(2024, 3, 1, 10, 30, 0, 0, tokyo),
+		"Mar 1 2024":          time.Date(2024, 3, 1, 0, 0, 0, 0, tokyo),
+	} {
+		if got, err := bind(val); err != nil || !got.Equal(want) {
+			t.Errorf("%s = %v, %v", val, got, err)
+		}
+	}
+	out := map[string]string{}
+	Unbind(out, "d", time.Date(2024, 2, 29, 15, 0, 0, 0, time.UTC))
+	if out["d"] != "01/03/2024" {
+		t.Errorf("unbind = %q", out["d"])
+	}
+
+	c.RunModeSection.NewKey(IniFormatTimeZone, "Mars/Olympus")
+	if err := SetTimeFormats(c); err == nil {
+		t.Error("unknown time zone accepted")
+	}
+}
+
+func TestAppTimeFormats(t *testing.T) {
+	withTestLog(t)
+	a := newTestApp(t)
+	a.Config.RunModeSection.NewKey(IniFormatDate, "02/01/2006")
+	a.Config.RunModeSection.NewKey(IniFormatTimeZone, "Asia/Tokyo")
+	if err := a.Startup(); err != nil {
+		t.Fatal(err)
+	}
+
+	typ := reflect.TypeOf(time.Time{})
+	p := &Params{Values: url.Values{"d": {"01/03/2024"}}, Binders: a.Binders}
+	v, err := BindE(p, "d", typ)
+	if d := v.Interface().(time.Time); err != nil || d.Day() != 1 || d.Month() != 3 || d.Location().String() != "Asia/Tokyo" {
+		t.Errorf("app binders: %v, %v", d, err)
+	}
+	out := map[string]string{}
+	a.Binders.Unbind(out, "d", v.Interface())
+	if out["d"] != "01/03/2024" {
+		t.Errorf("app unbind = %q", out["d"])
+	}
+	if _, err := BindValueE("01/03/2024", typ); err == nil || DateFormat != DefaultDateFormat {
+		t.Error("app formats leaked to the global ones")
+	}
+
+	// The global formats are set while times are bound.
+	c := NewConfigWithoutFile("test")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetTimeFormats(c)
+		}()
+		go func() {
+			defer wg.Done()
+			BindValueE("2024-03-01", typ)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDurationBinder(t *testing.T) {
+	withTestLog(t)
+	typ := reflect.TypeOf(time.Duration(0))
+	for val, want := range map[string]time.Duration{"1h30m": 90 * time.Minute, "45": 45 * time.Second, "": 0} {
+		if v, err := BindValueE(val, typ); err != nil || v.Interface() != want {
+			t.Errorf("%q = %v, %v", val, v, err)
+		}
+	}
+	if _, err := BindValueE("soon", typ); err == nil {
+		t.Error("soon bound")
+	}
+	out := map[string]string{}
+	Unbind(out, "d", 90*time.Second)
+	if out["d"] != "1m30s" {
+		t.Errorf("unbind = %q", out["d"])
+	}
+}
+
+type testSearch struct {
+	Query   string            `form:"q"`
+	Tags    []string          `form:"tags"`
+	Page    int               `form:"page,omitempty"`
+	Filters map[string]string `form:"f"`
+	Owner   testUser          `form:"owner"`
+	Since   *time.Time        `form:"since"`
+}
+
+func TestEncodeQuery(t *testing.T) {
+	withTestLog(t)
+	in := testSearch{
+		Query:   "go & more",
+		Tags:    []string{"a", "b"},
+		Filters: map[string]string{"lang": "go"},
+		Owner:   testUser{Name: "rob", Age: 40},
+	}
+	values := EncodeQuery(&in)
+	want := "f%5Blang%5D=go&owner.Age=40&owner.Name=rob&q=go+%26+more&tags%5B0%5D=a&tags%5B1%5D=b"
+	if got := values.Encode(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	var out testSearch
+	p := &Params{Values: values}
+	if err := p.BindStruct(&out); err != nil || !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip %+v, %v", out, err)
+	}
+
+	m := EncodeQuery(map[string]interface{}{"id": 7, "ids": []int{1, 2}, "none": nil})
+	if m.Encode() != "id=7&ids%5B0%5D=1&ids%5B1%5D=2" {
+		t.Errorf("map = %s", m.Encode())
+	}
+	if len(EncodeQuery(nil)) != 0 || len(EncodeQuery(42)) != 0 {
+		t.Error("values for nil or int")
+	}
+}
+
+func TestEncodeQueryPrecision(t *testing.T) {
+	type sample struct {
+		F   float64
+		F32 float32
+		T   time.Time
+	}
+	at := func(h, m, s, ns int) time.Time { return time.Date(2024, 1, 2, h, m, s, ns, time.UTC) }
+	for _, c := range []struct {
+		in   sample
+		want string
+	}{
+		{sample{0.00000012, 1.1, at(3, 4, 5, 0)}, "F=1.2e-07&F32=1.1&T=2024-01-02T03%3A04%3A05Z"},
+		{sample{1e300, 0, at(3, 4, 5, 123456789)}, "F=1e%2B300&F32=0&T=2024-01-02T03%3A04%3A05.123456789Z"},
+		{sample{-2.5, 0, at(3, 4, 0, 0)}, "F=-2.5&F32=0&T=2024-01-02+03%3A04"},
+		{sample{0, 0, at(0, 0, 0, 0)}, "F=0&F32=0&T=2024-01-02"},
+	} {
+		values := EncodeQuery(c.in)
+		if got := values.Encode(); got != c.want {
+			t.Errorf("got  %s\nwant %s", got, c.want)
+		}
+		var out sample
+		if err := (&Params{Values: values}).BindStruct(&out); err != nil || out.F != c.in.F || out.F32 != c.in.F32 || !out.T.Equal(c.in.T) {
+			t.Errorf("round trip %+v, %v", out, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package goboot
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+)
+
+// bindersMu guards TypeBinders and KindBinders.
+var bindersMu sync.RWMutex
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// RegisterBinder sets the global binder of typ. Unlike writing TypeBinders,
+// it is safe while requests are bound.
+func RegisterBinder(typ reflect.Type, b Binder) {
+	bindersMu.Lock()
+	defer bindersMu.Unlock()
+	TypeBinders[typ] = b
+}
+
+// RegisterKindBinder sets the global binder of the types of kind, used when
+// typ has no binder of its own. It is safe while requests are bound.
+func RegisterKindBinder(kind reflect.Kind, b Binder) {
+	bindersMu.Lock()
+	defer bindersMu.Unlock()
+	KindBinders[kind] = b
+}
+
+// BinderRegistry holds binders overriding the ones of its parent, or the
+// global ones for a nil parent. The binders of Params.Binders bind the params
+// and the values nested in them, the Unbind and EncodeQuery methods of the
+// same registry encode them back. A nil *BinderRegistry has the global
+// binders only.
+type BinderRegistry struct {
+	parent *BinderRegistry
+
+	mu    sync.RWMutex
+	types map[reflect.Type]Binder
+	kinds map[reflect.Kind]Binder
+}
+
+// NewBinderRegistry returns an empty registry falling back to parent, or to
+// the global binders when parent is nil.
+func NewBinderRegistry(parent *BinderRegistry) *BinderRegistry {
+	return &BinderRegistry{
+		parent: parent,
+		types:  make(map[reflect.Type]Binder),
+		kinds:  make(map[reflect.Kind]Binder),
+	}
+}
+
+// Register sets the binder of typ.
+func (r *BinderRegistry) Register(typ reflect.Type, b Binder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[typ] = b
+}
+
+// RegisterKind sets the binder of the types of kind without a binder of their
+// own.
+func (r *BinderRegistry) RegisterKind(kind reflect.Kind, b Binder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.kinds[kind] = b
+}
+
+func (r *BinderRegistry) typeBinder(typ reflect.Type) (Binder, bool) {
+	for ; r != nil; r = r.parent {
+		r.mu.RLock()
+		b, ok := r.types[typ]
+		r.mu.RUnlock()
+		if ok {
+			return b, true
+		}
+	}
+	bindersMu.RLock()
+	defer bindersMu.RUnlock()
+	b, ok := TypeBinders[typ]
+	return b, ok
+}
+
+func (r *BinderRegistry) kindBinder(kind reflect.Kind) (Binder, bool) {
+	for ; r != nil; r = r.parent {
+		r.mu.RLock()
+		b, ok := r.kinds[kind]
+		r.mu.RUnlock()
+		if ok {
+			return b, true
+		}
+	}
+	bindersMu.RLock()
+	defer bindersMu.RUnlock()
+	b, ok := KindBinders[kind]
+	return b, ok
+}
+
+// binderFor returns the binder of typ from r and the global binders: the
+// binders of the type first, then TextBinder for the types implementing
+// encoding.TextUnmarshaler (encoding.TextMarshaler to unbind), then the
+// binders of the kind.
+func (r *BinderRegistry) binderFor(typ reflect.Type, unbind bool) (Binder, bool) {
+	if b, ok := r.typeBinder(typ); ok {
+		return b, true
+	}
+	if unbind && typ.Implements(textMarshalerType) || !unbind && reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return TextBinder, true
+	}
+	return r.kindBinder(typ.Kind())
+}
+
+// Unbind serializes val to one or more URL parameters of the given name with
+// the binders of r, values nested in val included.
+func (r *BinderRegistry) Unbind(output map[string]string, name string, val interface{}) {
+	if val == nil {
+		return
+	}
+	binder, found := r.binderFor(reflect.TypeOf(val), true)
+	if !found {
+		return
+	}
+	switch {
+	case binder.unbindWith != nil:
+		binder.unbindWith(r, output, name, val)
+	case binder.Unbind != nil:
+		binder.Unbind(output, name, val)
+	default:
+		defaultLog().Errorf("revel/binder: can not unbind %s=%s", name, val)
+	}
+}
+
+// EncodeQuery is like the package EncodeQuery but unbinds with the binders of
+// r, so that params bound with r decode the values back.
+func (r *BinderRegistry) EncodeQuery(v interface{}) url.Values {
+	if values, ok := v.(url.Values); ok {
+		return values
+	}
+	output := make(map[string]string)
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		unbindStruct(r, output, "", rv.Interface())
+	case reflect.Map:
+		for _, key := range rv.MapKeys() {
+			r.Unbind(output, fmt.Sprint(key.Interface()), rv.MapIndex(key).Interface())
+		}
+	}
+
+	values := make(url.Values, len(output))
+	for k, v := range output {
+		values.Set(k, v)
+	}
+	return values
+}
+
+// TextBinder binds the types implementing encoding.TextUnmarshaler, and
+// unbinds the ones implementing encoding.TextMarshaler.
+var TextBinder = newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+	if len(val) == 0 {
+		return reflect.Zero(typ), nil
+	}
+	v := reflect.New(typ)
+	if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val)); err != nil {
+		return reflect.Zero(typ), err
+	}
+	return v.Elem(), nil
+}), func(output map[string]string, name string, val interface{}) {
+	if v := reflect.ValueOf(val); v.Kind() == reflect.Ptr && v.IsNil() {
+		return
+	}
+	if b, err := val.(encoding.TextMarshaler).MarshalText(); err == nil {
+		output[name] = string(b)
+	} else {
+		defaultLog().Errorf("revel/binder: can not unbind %s: %v", name, err)
+	}
+})
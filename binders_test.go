@@ -0,0 +1,146 @@
+package goboot
+
+import (
+	"errors"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testLevel implements encoding.TextUnmarshaler and encoding.TextMarshaler.
+type testLevel int
+
+func (l *testLevel) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+func (l testLevel) MarshalText() ([]byte, error) {
+	return []byte([]string{"", "low", "high"}[l]), nil
+}
+
+type testAlert struct {
+	Level  testLevel   `form:"level"`
+	Levels []testLevel `form:"levels"`
+	Peer   *testLevel  `form:"peer"`
+	IP     net.IP      `form:"ip"`
+}
+
+func TestTextBinder(t *testing.T) {
+	withTestLog(t)
+	p := &Params{Values: url.Values{"level": {"high"}, "levels[0]": {"low"}, "levels[1]": {"high"}, "peer": {"low"}, "ip": {"10.0.0.1"}}}
+	var a testAlert
+	if err := p.BindStruct(&a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Level != 2 || !reflect.DeepEqual(a.Levels, []testLevel{1, 2}) || a.Peer == nil || *a.Peer != 1 || !a.IP.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Errorf("bound %+v", a)
+	}
+
+	p = &Params{Values: url.Values{"level": {"loud"}}}
+	errs, ok := p.BindStruct(&a).(ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Field != "level" || errs[0].Rule != "type" {
+		t.Errorf("errors %v", errs)
+	}
+
+	values := EncodeQuery(testAlert{Level: 1, Levels: []testLevel{2}, IP: net.IPv4(10, 0, 0, 2)})
+	if got := values.Encode(); got != "ip=10.0.0.2&level=low&levels%5B0%5D=high" {
+		t.Errorf("encoded %s", got)
+	}
+}
+
+func TestBinderRegistry(t *testing.T) {
+	withTestLog(t)
+	upper := newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		return reflect.ValueOf(strings.ToUpper(val)), nil
+	}), nil)
+	suffix := newBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		return reflect.ValueOf(val + "!"), nil
+	}), nil)
+	stringType := reflect.TypeOf("")
+
+	a := newTestApp(t)
+	a.Binders.Register(stringType, upper)
+	child := NewBinderRegistry(a.Binders)
+	child.RegisterKind(reflect.Int, Binder{BindE: ValueBinderE(func(string, reflect.Type) (reflect.Value, error) {
+		return reflect.ValueOf(7), nil
+	})})
+
+	type form struct {
+		Name string
+		N    int
+		Tags []string
+	}
+	values := url.Values{"Name": {"rob"}, "N": {"1"}, "Tags[0]": {"a"}}
+	bind := func(binders *BinderRegistry) form {
+		var f form
+		p := &Params{Values: values, Binders: binders}
+		if err := p.BindStruct(&f); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	if f := bind(nil); f.Name != "rob" || f.N != 1 || f.Tags[0] != "a" {
+		t.Errorf("global binders: %+v", f)
+	}
+	if f := bind(a.Binders); f.Name != "ROB" || f.N != 1 || f.Tags[0] != "A" {
+		t.Errorf("app binders: %+v", f)
+	}
+	if f := bind(child); f.Name != "ROB" || f.N != 7 {
+		t.Errorf("child binders: %+v", f)
+	}
+	child.Register(stringType, suffix)
+	if f := bind(child); f.Name != "rob!" || f.Tags[0] != "a!" {
+		t.Errorf("overridden child binders: %+v", f)
+	}
+
+	r := httptest.NewRequest("GET", "/?Name=ann", nil)
+	p := &Params{}
+	if err := a.ParseParams(p, r); err != nil || p.Binders != a.Binders {
+		t.Fatalf("ParseParams: %v", err)
+	}
+	var name string
+	p.Bind(&name, "Name")
+	if name != "ANN" {
+		t.Errorf("bound %q", name)
+	}
+}
+
+func TestRegisterBinderConcurrently(t *testing.T) {
+	withTestLog(t)
+	typ := reflect.TypeOf(testCelsius(0))
+	defer func() {
+		bindersMu.Lock()
+		delete(TypeBinders, typ)
+		bindersMu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	p := &Params{Values: url.Values{"t": {"20"}}}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterBinder(typ, FloatBinder)
+		}()
+		go func() {
+			defer wg.Done()
+			BindE(p, "t", typ)
+		}()
+	}
+	wg.Wait()
+	if v, err := BindE(p, "t", typ); err != nil || v.Float() != 20 {
+		t.Errorf("bound %v, %v", v, err)
+	}
+}
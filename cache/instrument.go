@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/e2u/goboot/metrics"
+)
+
+var (
+	commandDuration = metrics.NewHistogram("goboot_redis_command_duration_seconds", "Redis command latency, by command.", nil, "command")
+	commandErrors   = metrics.NewCounter("goboot_redis_command_errors_total", "Failed Redis commands, by command.", "command")
+)
+
+// defaultDialTimeout 同 redis.Options.DialTimeout 的默认值
+const defaultDialTimeout = 5 * time.Second
+
+// InstrumentDialer 包装 redis.Options.Dialer, 记录经由其连接发出的每个命令的耗时和错误,
+// 包括 pipeline 和 Client 上的所有命令. dial 为 nil 时以 TCP 连接 addr.
+func InstrumentDialer(addr string, dial func() (net.Conn, error)) func() (net.Conn, error) {
+	if dial == nil {
+		dial = func() (net.Conn, error) {
+			return net.DialTimeout("tcp", addr, defaultDialTimeout)
+		}
+	}
+	return func() (net.Conn, error) {
+		conn, err := dial()
+		if err != nil {
+			return nil, err
+		}
+		return &instrumentedConn{Conn: conn}, nil
+	}
+}
+
+// observe 记录命令耗时, 错误回复和连接错误计入 commandErrors, 空回复 (redis.Nil) 不算作错误
+func observe(command string, start time.Time, failed bool) {
+	commandDuration.ObserveSince(start, command)
+	if failed {
+		commandErrors.Inc(command)
+	}
+}
+
+// instrumentedConn 在写入命令时计时, 在读到对应的回复时记录耗时.
+// Redis 按命令的顺序回复, 没有待回复命令时读到的是订阅消息, 不做记录.
+type instrumentedConn struct {
+	net.Conn
+
+	mu      sync.Mutex
+	pending []pendingCommand
+	replies replyScanner
+}
+
+type pendingCommand struct {
+	name  string
+	start time.Time
+}
+
+func (c *instrumentedConn) Write(b []byte) (int, error) {
+	now := time.Now()
+	c.mu.Lock()
+	for _, name := range commandNames(b) {
+		c.pending = append(c.pending, pendingCommand{name, now})
+	}
+	c.mu.Unlock()
+
+	n, err := c.Conn.Write(b)
+	if err != nil {
+		c.fail()
+	}
+	return n, err
+}
+
+func (c *instrumentedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.mu.Lock()
+	for _, failed := range c.replies.feed(b[:n]) {
+		if len(c.pending) == 0 {
+			continue
+		}
+		cmd := c.pending[0]
+		c.pending = c.pending[1:]
+		observe(cmd.name, cmd.start, failed)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		c.fail()
+	}
+	return n, err
+}
+
+// fail 记录待回复的命令为失败, 出错后 Client 不再使用这个连接
+func (c *instrumentedConn) fail() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cmd := range c.pending {
+		observe(cmd.name, cmd.start, true)
+	}
+	c.pending = nil
+}
+
+// commandNames 返回 b 中 RESP 格式的命令名, 如 *2\r\n$3\r\nGET\r\n$1\r\nk\r\n 为 get
+func commandNames(b []byte) []string {
+	var names []string
+	for len(b) > 0 && b[0] == '*' {
+		n, rest, ok := respInt(b[1:])
+		if !ok {
+			break
+		}
+		b = rest
+		name := "unknown"
+		for i := 0; i < n; i++ {
+			if len(b) == 0 || b[0] != '$' {
+				return append(names, name)
+			}
+			l, rest, ok := respInt(b[1:])
+			if !ok || l < 0 || len(rest) < l+2 {
+				return append(names, name)
+			}
+			if i == 0 {
+				name = strings.ToLower(string(rest[:l]))
+			}
+			b = rest[l+2:]
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// respInt 读取 \r\n 结尾的整数, 返回其后的数据
+func respInt(b []byte) (int, []byte, bool) {
+	i := bytes.Index(b, []byte("\r\n"))
+	if i < 0 {
+		return 0, nil, false
+	}
+	n, err := strconv.Atoi(string(b[:i]))
+	return n, b[i+2:], err == nil
+}
+
+// replyScanner 逐段读取 RESP 回复, 识别每个完整回复的结束; 批量数据只跳过, 不做缓存.
+type replyScanner struct {
+	line   []byte // 未读完的类型行
+	skip   int    // 待跳过的批量数据及其 \r\n 的字节数
+	arrays []int  // 外层数组尚未读完的元素个数
+	failed bool   // 当前回复是否为错误回复
+}
+
+// feed 读取 b, 按顺序返回其中结束的回复是否为错误回复
+func (s *replyScanner) feed(b []byte) []bool {
+	var replies []bool
+	for len(b) > 0 {
+		if s.skip > 0 {
+			n := s.skip
+			if n > len(b) {
+				n = len(b)
+			}
+			s.skip -= n
+			b = b[n:]
+			if s.skip == 0 {
+				replies = s.end(replies)
+			}
+			continue
+		}
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			s.line = append(s.line, b...)
+			break
+		}
+		line := append(s.line, b[:i+1]...)
+		b = b[i+1:]
+		replies = s.header(line, replies)
+		s.line = line[:0]
+	}
+	return replies
+}
+
+// header 处理一个类型行: 简单字符串, 错误, 整数, 批量数据或数组的开始
+func (s *replyScanner) header(line []byte, replies []bool) []bool {
+	if len(line) == 0 {
+		return replies
+	}
+	if len(s.arrays) == 0 {
+		s.failed = line[0] == '-'
+	}
+	n, _ := strconv.Atoi(string(bytes.TrimRight(line[1:], "\r\n")))
+	switch line[0] {
+	case '$':
+		if n >= 0 {
+			s.skip = n + 2
+			return replies
+		}
+	case '*':
+		if n > 0 {
+			s.arrays = append(s.arrays, n)
+			return replies
+		}
+	}
+	return s.end(replies)
+}
+
+// end 结束一个值, 元素全部读完的外层数组随之结束
+func (s *replyScanner) end(replies []bool) []bool {
+	for len(s.arrays) > 0 {
+		last := len(s.arrays) - 1
+		if s.arrays[last]--; s.arrays[last] > 0 {
+			return replies
+		}
+		s.arrays = s.arrays[:last]
+	}
+	return append(replies, s.failed)
+}
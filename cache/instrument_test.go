@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/e2u/goboot/metrics"
+)
+
+func TestCommandNames(t *testing.T) {
+	b := []byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n*3\r\n$3\r\nset\r\n$1\r\nk\r\n$2\r\nv\n\r\n")
+	if names := commandNames(b); !reflect.DeepEqual(names, []string{"get", "set"}) {
+		t.Errorf("names %q", names)
+	}
+}
+
+func TestReplyScanner(t *testing.T) {
+	replies := "+OK\r\n$-1\r\n-ERR wrong\r\n*3\r\n$1\r\na\r\n*1\r\n:1\r\n$3\r\nb\r\n\r\n*0\r\n"
+	// Feeding byte by byte must give the same replies as feeding at once.
+	for _, size := range []int{len(replies), 1} {
+		var s replyScanner
+		var got []bool
+		for b := []byte(replies); len(b) > 0; {
+			n := size
+			if n > len(b) {
+				n = len(b)
+			}
+			got = append(got, s.feed(b[:n])...)
+			b = b[n:]
+		}
+		if want := []bool{false, false, true, false, false}; !reflect.DeepEqual(got, want) {
+			t.Errorf("chunk %d: replies %v, want %v", size, got, want)
+		}
+	}
+}
+
+func TestInstrumentDialer(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 512)
+		server.Read(buf)
+		server.Write([]byte("$-1\r\n-ERR oom\r\n"))
+	}()
+
+	conn, _ := InstrumentDialer("", func() (net.Conn, error) { return client, nil })()
+	conn.Write([]byte("*2\r\n$3\r\nGET\r\n$4\r\nmiss\r\n*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"))
+	buf := make([]byte, 512)
+	for n := 0; n < len("$-1\r\n-ERR oom\r\n"); {
+		m, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n += m
+	}
+
+	var out bytes.Buffer
+	metrics.Default.WritePrometheus(&out)
+	for _, want := range []string{
+		`goboot_redis_command_duration_seconds_count{command="get"} 1`,
+		`goboot_redis_command_duration_seconds_count{command="set"} 1`,
+		`goboot_redis_command_errors_total{command="set"} 1`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if strings.Contains(out.String(), `goboot_redis_command_errors_total{command="get"}`) {
+		t.Error("nil reply counted as error")
+	}
+}
@@ -11,11 +11,13 @@ type Redis struct {
 	*redis.Client
 }
 
+// NewRedis 连接 addr 的 db, Client 上的每个命令都记录耗时和错误, 见 InstrumentDialer
 func NewRedis(addr string, db int) *Redis {
 	return &Redis{
 		Client: redis.NewClient(&redis.Options{
-			Addr: addr,
-			DB:   db,
+			Addr:   addr,
+			DB:     db,
+			Dialer: InstrumentDialer(addr, nil),
 		}),
 	}
 }
@@ -134,6 +134,36 @@ func (c *ConfigContext) LogDumpHttpResponseBody() bool {
 	return c.MustBool(IniDumpHttpResponseBody)
 }
 
+// LogDumpHttpBodyMax is the number of body bytes dumped, DefaultDumpBodyMax
+// when not set.
+func (c *ConfigContext) LogDumpHttpBodyMax() int {
+	if n := c.MustInt(IniDumpHttpBodyMax, DefaultDumpBodyMax); n > 0 {
+		return n
+	}
+	return DefaultDumpBodyMax
+}
+
+// UploadLimits returns the limits set by the http.upload.* and http.body.*
+// keys, in bytes. http.body.max_size defaults to DefaultBodyMaxSize, 0 or a
+// negative value removes the limit. A nil c returns the defaults.
+func (c *ConfigContext) UploadLimits() UploadLimits {
+	l := UploadLimits{MaxMemory: DefaultUploadMaxMemory, MaxBodySize: DefaultBodyMaxSize}
+	if c == nil {
+		return l
+	}
+	if n := c.MustInt(IniHttpUploadMaxMemory, DefaultUploadMaxMemory); n > 0 {
+		l.MaxMemory = int64(n)
+	}
+	if n := c.MustInt(IniHttpBodyMaxSize, DefaultBodyMaxSize); n > 0 {
+		l.MaxBodySize = int64(n)
+	} else {
+		l.MaxBodySize = 0
+	}
+	l.MaxFileSize = int64(c.MustInt(IniHttpUploadMaxFile))
+	l.Types = c.MustStringArray(IniHttpUploadTypes, ",")
+	return l
+}
+
 func (c *ConfigContext) SetLogDumpHttpRequest(b bool) {
 	c.RunModeSection.Key(IniDumpHttpRequest).SetValue(strconv.FormatBool(b))
 }
@@ -321,3 +351,21 @@ func (c *ConfigContext) MustStringArray(key string, sep string, defaultVal ...st
 	}
 	return strings.Split(defaultVal[0], sep)
 }
+
+// MustStringMap returns the values of all keys starting with prefix, keyed by
+// the rest of the key name. Keys of the run mode section override the default
+// section.
+func (c *ConfigContext) MustStringMap(prefix string) map[string]string {
+	m := make(map[string]string)
+	for _, sec := range []*ini.Section{c.DefaultSection, c.RunModeSection} {
+		if sec == nil {
+			continue
+		}
+		for _, k := range sec.Keys() {
+			if name := k.Name(); strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+				m[name[len(prefix):]] = k.String()
+			}
+		}
+	}
+	return m
+}
@@ -4,11 +4,42 @@ const (
 	IniLogOutput            = "log.output"
 	IniLevel                = "log.level"
 	IniLogFormat            = "log.format"
+	IniLogFormatPattern     = "log.format.pattern"
+	IniFormatDate           = "format.date"
+	IniFormatDateTime       = "format.datetime"
+	IniFormatTimePrefix     = "format.time."
+	IniFormatTimeZone       = "format.timezone"
+	IniLogRedact            = "log.redact"
+	IniLogRedactKeys        = "log.redact.keys"
+	IniLogRedactPattern     = "log.redact.pattern"
+	IniLogRedactMask        = "log.redact.mask"
 	IniHttpLogOutput        = "http.log.output"
 	IniHttpLogFormat        = "http.log.format"
+	IniHttpProxies          = "http.proxies"
+	IniHttpAddr             = "http.addr"
+	IniHttpReadTimeout      = "http.timeout.read"
+	IniHttpHeaderTimeout    = "http.timeout.read_header"
+	IniHttpWriteTimeout     = "http.timeout.write"
+	IniHttpIdleTimeout      = "http.timeout.idle"
+	IniHttpTLSCert          = "http.tls.cert"
+	IniHttpTLSKey           = "http.tls.key"
+	IniHttpUploadMaxMemory  = "http.upload.max_memory"
+	IniHttpUploadMaxFile    = "http.upload.max_file_size"
+	IniHttpUploadTypes      = "http.upload.types"
+	IniHttpUploadTempMaxAge = "http.upload.tmp_max_age"
+	IniHttpBodyMaxSize      = "http.body.max_size"
+	IniAppStartupTimeout    = "app.startup.timeout"
+	IniAppShutdownTimeout   = "app.shutdown.timeout"
+	IniAdminAddr            = "admin.addr"
+	IniAdminUser            = "admin.auth.user"
+	IniAdminPassword        = "admin.auth.password"
+	IniAdminToken           = "admin.auth.token"
+	IniAdminAllow           = "admin.allow"
+	IniPprofAddr            = "pprof.addr"
 	IniModeDev              = "mode.dev"
 	IniDumpHttpRequest      = "log.dump.http.request"
 	IniDumpHttpRequestBody  = "log.dump.http.request.body"
 	IniDumpHttpResponse     = "log.dump.http.response"
 	IniDumpHttpResponseBody = "log.dump.http.response.body"
+	IniDumpHttpBodyMax      = "log.dump.http.body.max"
 )
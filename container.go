@@ -0,0 +1,257 @@
+package goboot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ContainerHookName names the startup hook that runs the providers and the
+// invoked functions, named hooks can list it in After.
+const ContainerHookName = "goboot.container"
+
+// containerStopOrder closes provided values after the stop hooks registered
+// with the default order, but before the admin server stops.
+const containerStopOrder = 0
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	configType  = reflect.TypeOf((*ConfigContext)(nil))
+	loggerType  = reflect.TypeOf((*Logger)(nil)).Elem()
+	appType     = reflect.TypeOf((*App)(nil))
+	builtinType = map[reflect.Type]bool{configType: true, loggerType: true, appType: true}
+)
+
+type provider struct {
+	name   string
+	fn     reflect.Value
+	types  []reflect.Type
+	hasErr bool
+}
+
+// container builds the values of the registered providers and passes them
+// to the invoked functions.
+type container struct {
+	mu        sync.Mutex
+	providers []*provider
+	byType    map[reflect.Type]*provider
+	invokes   []reflect.Value
+	errs      MultiError
+	hooked    bool
+
+	values map[reflect.Type]reflect.Value
+}
+
+// Provide registers a constructor with the default App, e.g.
+//
+//	g.Provide(func(cfg *g.ConfigContext) (*cache.Redis, error) {
+//		return cache.NewRedis(cfg.MustString("redis.addr"), 0), nil
+//	})
+func Provide(constructor interface{}) {
+	defaultApp.Provide(constructor)
+}
+
+// Invoke registers f with the default App, e.g.
+//
+//	g.Invoke(func(r *cache.Redis) {
+//		locker = r
+//	})
+func Invoke(f interface{}) {
+	defaultApp.Invoke(f)
+}
+
+// Provide registers a constructor. Its parameters are provided values or the
+// App's *ConfigContext, Logger and *App; it returns one or more values of
+// distinct types, optionally followed by an error. Every provider runs once
+// during Startup, after the providers of its parameters. A provided value
+// implementing io.Closer is closed at shutdown, after the stop hooks with the
+// default order.
+//
+// Registration errors, such as a type provided twice, are returned by
+// Startup.
+func (a *App) Provide(constructor interface{}) {
+	c := a.containerHook()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn := reflect.ValueOf(constructor)
+	if fn.Kind() != reflect.Func {
+		c.errs = append(c.errs, fmt.Errorf("provide %T: not a function", constructor))
+		return
+	}
+
+	p := &provider{name: funcName(constructor), fn: fn}
+	typ := fn.Type()
+	for i := 0; i < typ.NumOut(); i++ {
+		out := typ.Out(i)
+		switch {
+		case out == errorType && i == typ.NumOut()-1:
+			p.hasErr = true
+		case out == errorType:
+			c.errs = append(c.errs, fmt.Errorf("provide %s: error must be the last result", p.name))
+			return
+		case builtinType[out]:
+			c.errs = append(c.errs, fmt.Errorf("provide %s: %s is provided by the App", p.name, out))
+			return
+		case c.byType[out] != nil:
+			c.errs = append(c.errs, fmt.Errorf("provide %s: %s already provided by %s", p.name, out, c.byType[out].name))
+			return
+		default:
+			p.types = append(p.types, out)
+		}
+	}
+	if len(p.types) == 0 {
+		c.errs = append(c.errs, fmt.Errorf("provide %s: no value returned", p.name))
+		return
+	}
+
+	if c.byType == nil {
+		c.byType = make(map[reflect.Type]*provider)
+	}
+	for _, t := range p.types {
+		c.byType[t] = p
+	}
+	c.providers = append(c.providers, p)
+}
+
+// Invoke registers f to be called during Startup, once every provider ran.
+// The parameters of f are resolved like those of a provider, f returns
+// nothing or an error.
+func (a *App) Invoke(f interface{}) {
+	c := a.containerHook()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn := reflect.ValueOf(f)
+	switch {
+	case fn.Kind() != reflect.Func:
+		c.errs = append(c.errs, fmt.Errorf("invoke %T: not a function", f))
+	case fn.Type().NumOut() > 1 || fn.Type().NumOut() == 1 && fn.Type().Out(0) != errorType:
+		c.errs = append(c.errs, fmt.Errorf("invoke %s: may only return an error", funcName(f)))
+	default:
+		c.invokes = append(c.invokes, fn)
+	}
+}
+
+// containerHook registers the container startup hook on first use. It runs
+// before every hook registered with OnAppStart.
+func (a *App) containerHook() *container {
+	c := &a.container
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.hooked {
+		c.hooked = true
+		a.OnAppStartHook(StartupHook{Name: ContainerHookName, Order: math.MinInt32, Func: a.resolveContainer})
+	}
+	return c
+}
+
+// resolveContainer runs every provider in dependency order, then the invoked
+// functions in registration order.
+func (a *App) resolveContainer() error {
+	c := &a.container
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.errs.ErrorOrNil(); err != nil {
+		return err
+	}
+	c.values = make(map[reflect.Type]reflect.Value)
+	for _, p := range c.providers {
+		if _, err := a.resolve(p.types[0], nil); err != nil {
+			return err
+		}
+	}
+
+	for _, fn := range c.invokes {
+		args, err := a.resolveArgs(fn.Type(), nil)
+		if err != nil {
+			return fmt.Errorf("invoke %s: %w", funcName(fn.Interface()), err)
+		}
+		if out := fn.Call(args); len(out) == 1 && !out[0].IsNil() {
+			return fmt.Errorf("invoke %s: %w", funcName(fn.Interface()), out[0].Interface().(error))
+		}
+	}
+	return nil
+}
+
+// resolve returns the value of typ, running its provider when needed. path
+// holds the types being resolved, to report dependency cycles.
+func (a *App) resolve(typ reflect.Type, path []reflect.Type) (reflect.Value, error) {
+	switch typ {
+	case configType:
+		return reflect.ValueOf(a.Config), nil
+	case loggerType:
+		v := reflect.New(loggerType).Elem()
+		if a.Log != nil {
+			v.Set(reflect.ValueOf(a.Log))
+		}
+		return v, nil
+	case appType:
+		return reflect.ValueOf(a), nil
+	}
+
+	c := &a.container
+	if v, ok := c.values[typ]; ok {
+		return v, nil
+	}
+	p := c.byType[typ]
+	if p == nil {
+		return reflect.Value{}, fmt.Errorf("no provider for %s", typ)
+	}
+	for i, t := range path {
+		if c.byType[t] == p {
+			names := make([]string, 0, len(path)-i+1)
+			for _, t := range path[i:] {
+				names = append(names, t.String())
+			}
+			return reflect.Value{}, fmt.Errorf("dependency cycle: %s -> %s", strings.Join(names, " -> "), typ)
+		}
+	}
+
+	args, err := a.resolveArgs(p.fn.Type(), append(path, typ))
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("provide %s: %w", p.name, err)
+	}
+	out := p.fn.Call(args)
+	if p.hasErr {
+		if err := out[len(out)-1]; !err.IsNil() {
+			return reflect.Value{}, fmt.Errorf("provide %s: %w", p.name, err.Interface().(error))
+		}
+		out = out[:len(out)-1]
+	}
+
+	for i, v := range out {
+		c.values[p.types[i]] = v
+		a.closeOnStop(v)
+	}
+	return c.values[typ], nil
+}
+
+func (a *App) resolveArgs(typ reflect.Type, path []reflect.Type) ([]reflect.Value, error) {
+	args := make([]reflect.Value, typ.NumIn())
+	for i := range args {
+		v, err := a.resolve(typ.In(i), path)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+// closeOnStop registers a stop hook closing v when it is an io.Closer.
+func (a *App) closeOnStop(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		if v.IsNil() {
+			return
+		}
+	}
+	if closer, ok := v.Interface().(io.Closer); ok {
+		a.OnAppStop(closer.Close, containerStopOrder)
+	}
+}
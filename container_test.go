@@ -0,0 +1,91 @@
+package goboot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testDB struct {
+	dsn    string
+	closed *[]string
+}
+
+func (db *testDB) Close() error {
+	*db.closed = append(*db.closed, db.dsn)
+	return nil
+}
+
+type testRepo struct {
+	db *testDB
+}
+
+func TestContainer(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.RunModeSection.NewKey("db.dsn", "mem://test")
+
+	var closed []string
+	var got *testRepo
+	a.Invoke(func(r *testRepo, l Logger) {
+		got = r
+	})
+	a.Provide(func(db *testDB) *testRepo {
+		return &testRepo{db: db}
+	})
+	a.Provide(func(cfg *ConfigContext) (*testDB, error) {
+		return &testDB{dsn: cfg.MustString("db.dsn"), closed: &closed}, nil
+	})
+	hookRan := false
+	a.OnAppStart(func() error {
+		hookRan = got != nil
+		return nil
+	}, 0)
+
+	if err := a.Startup(); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.db.dsn != "mem://test" {
+		t.Fatalf("invoked with %+v", got)
+	}
+	if !hookRan {
+		t.Error("startup hook ran before the container")
+	}
+	if err := a.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"mem://test"}; !reflect.DeepEqual(closed, want) {
+		t.Errorf("closed %v, want %v", closed, want)
+	}
+}
+
+func TestContainerErrors(t *testing.T) {
+	boom := errors.New("boom")
+	cases := []struct {
+		setup func(a *App)
+		want  string
+	}{
+		{func(a *App) { a.Invoke(func(*testRepo) {}) }, "no provider for *goboot.testRepo"},
+		{func(a *App) {
+			a.Provide(func(*testRepo) *testDB { return nil })
+			a.Provide(func(*testDB) *testRepo { return nil })
+		}, "dependency cycle"},
+		{func(a *App) {
+			a.Provide(func() (*testDB, error) { return nil, boom })
+			a.Invoke(func(*testDB) {})
+		}, "boom"},
+		{func(a *App) {
+			a.Provide(func() *testDB { return nil })
+			a.Provide(func() *testDB { return nil })
+		}, "*goboot.testDB already provided"},
+		{func(a *App) { a.Invoke(func() error { return boom }) }, "boom"},
+		{func(a *App) { a.Provide("db") }, "not a function"},
+	}
+	for _, c := range cases {
+		a := newTestApp(t)
+		c.setup(a)
+		if err := a.Startup(); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("got %v, want %q", err, c.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package goboot
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// BodyDecoder decodes a request body into dest, a pointer.
+type BodyDecoder func(body []byte, dest interface{}) error
+
+// ProtoUnmarshaler is implemented by generated protobuf messages, it decodes
+// application/x-protobuf bodies.
+type ProtoUnmarshaler interface {
+	Unmarshal(b []byte) error
+}
+
+// MsgpUnmarshaler is implemented by the types generated by msgp, it decodes
+// application/msgpack bodies.
+type MsgpUnmarshaler interface {
+	UnmarshalMsg(b []byte) ([]byte, error)
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]BodyDecoder{}
+)
+
+func init() {
+	for _, t := range []string{"application/json", "text/json", "application/*+json"} {
+		RegisterBodyDecoder(t, json.Unmarshal)
+	}
+	for _, t := range []string{"application/xml", "text/xml", "application/*+xml"} {
+		RegisterBodyDecoder(t, xml.Unmarshal)
+	}
+	for _, t := range []string{"application/x-protobuf", "application/protobuf"} {
+		RegisterBodyDecoder(t, decodeProto)
+	}
+	for _, t := range []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"} {
+		RegisterBodyDecoder(t, decodeMsgp)
+	}
+}
+
+// RegisterBodyDecoder sets the decoder of the bodies of the given media type,
+// replacing the previous one. The subtype may be "*" for any subtype, or
+// "*+suffix" for structured syntax suffixes, e.g. "application/*+json".
+// ParseParams only reads the bodies having a decoder.
+//
+// e.g. with github.com/vmihailenco/msgpack
+//
+//	goboot.RegisterBodyDecoder("application/msgpack", msgpack.Unmarshal)
+func RegisterBodyDecoder(mediaType string, d BodyDecoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(mediaType)] = d
+}
+
+// bodyDecoder returns the decoder of mediaType, trying an exact match, then
+// the structured syntax suffix, then the type wildcard.
+func bodyDecoder(mediaType string) (BodyDecoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	if d, ok := decoders[mediaType]; ok {
+		return d, true
+	}
+	i := strings.Index(mediaType, "/")
+	if i < 0 {
+		return nil, false
+	}
+	if j := strings.LastIndex(mediaType, "+"); j > i {
+		if d, ok := decoders[mediaType[:i]+"/*"+mediaType[j:]]; ok {
+			return d, true
+		}
+	}
+	d, ok := decoders[mediaType[:i]+"/*"]
+	return d, ok
+}
+
+// isJSON reports whether mediaType is decoded as JSON.
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || mediaType == "text/json" ||
+		strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")
+}
+
+func decodeProto(body []byte, dest interface{}) error {
+	m, ok := dest.(ProtoUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%T does not implement ProtoUnmarshaler", dest)
+	}
+	return m.Unmarshal(body)
+}
+
+func decodeMsgp(body []byte, dest interface{}) error {
+	m, ok := dest.(MsgpUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%T does not implement MsgpUnmarshaler", dest)
+	}
+	_, err := m.UnmarshalMsg(body)
+	return err
+}
@@ -0,0 +1,115 @@
+package goboot
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testItem struct {
+	Name  string `json:"name" xml:"name" validate:"required"`
+	Count int    `json:"count" xml:"count"`
+}
+
+// testProto stands for a generated protobuf message.
+type testProto struct {
+	testItem
+}
+
+func (m *testProto) Unmarshal(b []byte) error {
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 {
+		return errors.New("bad message")
+	}
+	m.Name = parts[0]
+	return nil
+}
+
+func parseTestRequest(t *testing.T, contentType, body string) *Params {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/?q=1", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	p := &Params{}
+	ParseParams(p, r)
+	return p
+}
+
+func TestParseParamsMediaTypes(t *testing.T) {
+	withTestLog(t)
+	for _, ct := range []string{"application/json; charset=utf-8", "Application/JSON", "application/vnd.api+json"} {
+		p := parseTestRequest(t, ct, `{"name":"a","count":2}`)
+		var it testItem
+		if err := p.BindStruct(&it); err != nil || it != (testItem{"a", 2}) {
+			t.Errorf("%s: %+v, %v", ct, it, err)
+		}
+	}
+
+	for _, ct := range []string{"application/xml", "text/xml; charset=utf-8", "application/atom+xml"} {
+		p := parseTestRequest(t, ct, `<item><name>b</name><count>3</count></item>`)
+		var it testItem
+		if err := p.BindXML(&it); err != nil || it != (testItem{"b", 3}) {
+			t.Errorf("%s: %+v, %v", ct, it, err)
+		}
+		if p.JSON != nil {
+			t.Errorf("%s: JSON is set", ct)
+		}
+	}
+
+	p := parseTestRequest(t, "application/xml", `<item><count>3</count></item>`)
+	var it testItem
+	if _, ok := p.BindStruct(&it).(ValidationErrors); !ok {
+		t.Errorf("xml body not validated")
+	}
+
+	p = parseTestRequest(t, "application/x-www-form-urlencoded; charset=UTF-8", "name=c&count=4")
+	if p.Get("name") != "c" || p.Get("q") != "1" {
+		t.Errorf("form values = %v", p.Values)
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", "d")
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	fw.Write([]byte("hello"))
+	mw.Close()
+	p = parseTestRequest(t, mw.FormDataContentType(), buf.String())
+	if p.Get("name") != "d" || len(p.Files["file"]) != 1 {
+		t.Errorf("multipart = %v, %v", p.Values, p.Files)
+	}
+
+	p = parseTestRequest(t, "application/octet-stream", "raw")
+	if p.Body != nil || p.ContentType != "application/octet-stream" {
+		t.Errorf("undecodable body read: %q", p.Body)
+	}
+}
+
+func TestBodyDecoders(t *testing.T) {
+	withTestLog(t)
+	p := parseTestRequest(t, "application/x-protobuf", "e:5")
+	var m testProto
+	if err := p.BindBody(&m); err != nil || m.Name != "e" {
+		t.Errorf("protobuf = %+v, %v", m, err)
+	}
+	if err := p.BindBody(&testItem{}); err == nil {
+		t.Error("protobuf decoded into a plain struct")
+	}
+
+	RegisterBodyDecoder("text/x-pair", func(b []byte, dest interface{}) error {
+		parts := strings.SplitN(string(b), "=", 2)
+		dest.(*testItem).Name = parts[1]
+		return nil
+	})
+	defer func() {
+		decodersMu.Lock()
+		delete(decoders, "text/x-pair")
+		decodersMu.Unlock()
+	}()
+	p = parseTestRequest(t, "text/x-pair", "name=f")
+	var it testItem
+	if err := p.BindStruct(&it); err != nil || it.Name != "f" {
+		t.Errorf("custom decoder = %+v, %v", it, err)
+	}
+}
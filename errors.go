@@ -1 +1,28 @@
 package goboot
+
+import "strings"
+
+// MultiError collects the errors of several independent operations, e.g. the
+// stop hooks run at shutdown.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	msgs := make([]string, 0, len(m))
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap lets errors.Is and errors.As inspect every collected error.
+func (m MultiError) Unwrap() []error {
+	return m
+}
+
+// ErrorOrNil returns nil when no error was collected.
+func (m MultiError) ErrorOrNil() error {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
@@ -24,7 +24,9 @@ func main() {
 	g.Log.Warning("warning")
 	g.Log.Error("error")
 	g.Log.Critical("critical")
-	g.Startup()
+	if err := g.Startup(); err != nil {
+		g.Log.Critical(err)
+	}
 
 	fmt.Println("run mode key values")
 	for _, k := range g.Config.RunModeSection.KeyStrings() {
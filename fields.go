@@ -0,0 +1,46 @@
+package goboot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fields attaches structured key/value pairs to a log line, e.g.
+//
+//	Log.Info("user login", goboot.Fields{"user": id, "ip": ip})
+//
+// Text backends render them as sorted key=value pairs.
+type Fields map[string]interface{}
+
+func (f Fields) String() string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, f[k]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// FieldsOf merges all Fields found in args, later keys win.
+func FieldsOf(args []interface{}) Fields {
+	var fields Fields
+	for _, arg := range args {
+		f, ok := arg.(Fields)
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = make(Fields, len(f))
+		}
+		for k, v := range f {
+			fields[k] = v
+		}
+	}
+	return fields
+}
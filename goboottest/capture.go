@@ -0,0 +1,154 @@
+// Package goboottest provides helpers for testing applications built on goboot.
+package goboottest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/e2u/goboot"
+	logging "github.com/op/go-logging"
+)
+
+// Entry is a log record kept by a LogCapture.
+type Entry struct {
+	Time    time.Time
+	Level   logging.Level
+	Module  string
+	Message string
+	Fields  goboot.Fields
+}
+
+// LogCapture is an in-memory logging backend recording every entry at any
+// level.
+type LogCapture struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// CaptureLogs installs a LogCapture as the backend of goboot.Log and of the
+// default App for the duration of the test, and of the Log of each of apps.
+// Records go through the redaction of their App first, so the entries hold
+// what the App would write. The previous loggers and backends are restored
+// on cleanup.
+func CaptureLogs(t testing.TB, apps ...*goboot.App) *LogCapture {
+	c := &LogCapture{}
+
+	// Entries only reach the backend through a go-logging logger, swap out
+	// any other Logger implementation while capturing.
+	prevLog := goboot.Log
+	if _, ok := goboot.Log.(*logging.Logger); !ok {
+		goboot.Log = logging.MustGetLogger("goboottest")
+	}
+	// Default syncs the Log of the default App with goboot.Log.
+	def := goboot.Default()
+	prev := def.SetLogBackend(c.backend(goboot.LogRedactFilter))
+	t.Cleanup(func() {
+		def.SetLogBackend(prev)
+		goboot.Log = prevLog
+		goboot.Default()
+	})
+
+	for _, a := range apps {
+		c.captureApp(t, a)
+	}
+	return c
+}
+
+// captureApp gives a a logger of its own writing to c, the logger of a may
+// be shared and is left untouched.
+func (c *LogCapture) captureApp(t testing.TB, a *goboot.App) {
+	prevLog := a.Log
+	module := "goboottest"
+	if l, ok := a.Log.(*logging.Logger); ok {
+		module = l.Module
+	}
+	a.Log = logging.MustGetLogger(module)
+	prev := a.SetLogBackend(c.backend(a.LogRedactFilter))
+	t.Cleanup(func() {
+		a.SetLogBackend(prev)
+		a.Log = prevLog
+	})
+}
+
+// backend returns c behind the redaction of f.
+func (c *LogCapture) backend(f *goboot.RedactFilter) logging.LeveledBackend {
+	if f == nil {
+		return logging.AddModuleLevel(c)
+	}
+	return logging.AddModuleLevel(redactingCapture{c, f})
+}
+
+// redactingCapture redacts records like goboot.NewRedactBackend before c
+// records them, keeping their fields.
+type redactingCapture struct {
+	c      *LogCapture
+	filter *goboot.RedactFilter
+}
+
+func (rc redactingCapture) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	args, msg := rc.filter.RedactRecord(rec)
+	rc.c.add(Entry{
+		Time:    rec.Time,
+		Level:   level,
+		Module:  rec.Module,
+		Message: msg,
+		Fields:  goboot.FieldsOf(args),
+	})
+	return nil
+}
+
+// Log implements logging.Backend.
+func (c *LogCapture) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	c.add(Entry{
+		Time:    rec.Time,
+		Level:   level,
+		Module:  rec.Module,
+		Message: rec.Message(),
+		Fields:  goboot.FieldsOf(rec.Args),
+	})
+	return nil
+}
+
+func (c *LogCapture) add(e Entry) {
+	c.mu.Lock()
+	c.entries = append(c.entries, e)
+	c.mu.Unlock()
+}
+
+// Entries returns a copy of the recorded entries in logging order.
+func (c *LogCapture) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Entry(nil), c.entries...)
+}
+
+// Level returns the recorded entries of the given level.
+func (c *LogCapture) Level(level logging.Level) []Entry {
+	var entries []Entry
+	for _, e := range c.Entries() {
+		if e.Level == level {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Contains reports whether an entry of the given level has a message
+// containing substr.
+func (c *LogCapture) Contains(level logging.Level, substr string) bool {
+	for _, e := range c.Level(level) {
+		if strings.Contains(e.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset drops the recorded entries.
+func (c *LogCapture) Reset() {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+}
@@ -0,0 +1,70 @@
+package goboottest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e2u/goboot"
+	logging "github.com/op/go-logging"
+)
+
+func TestCaptureLogs(t *testing.T) {
+	var prev logging.LeveledBackend
+
+	t.Run("capture", func(t *testing.T) {
+		prev = goboot.LogBackend()
+		logs := CaptureLogs(t)
+
+		goboot.Log.Warning("disk almost full", goboot.Fields{"free": "1%"})
+		goboot.Log.Debugf("retry %d", 3)
+
+		entries := logs.Entries()
+		if len(entries) != 2 {
+			t.Fatalf("got %d entries, want 2", len(entries))
+		}
+		if entries[0].Level != logging.WARNING || entries[0].Fields["free"] != "1%" {
+			t.Error("warning entry", entries[0])
+		}
+		if !logs.Contains(logging.DEBUG, "retry 3") {
+			t.Error("debug entry", entries[1])
+		}
+
+		logs.Reset()
+		if len(logs.Entries()) != 0 {
+			t.Error("Reset")
+		}
+	})
+
+	if goboot.LogBackend() != prev {
+		t.Error("previous backend not restored")
+	}
+}
+
+func TestCaptureLogsApps(t *testing.T) {
+	cfg := goboot.NewConfigWithoutFile("test")
+	cfg.RunModeSection.NewKey(goboot.IniLogOutput, "off")
+	a := goboot.New(goboot.WithConfig(cfg))
+	t.Cleanup(func() { a.Shutdown() })
+	prevLog := a.Log
+	prevFilter := goboot.LogRedactFilter
+	goboot.LogRedactFilter, _ = goboot.NewRedactFilter(goboot.DefaultRedactKeys, nil)
+	t.Cleanup(func() { goboot.LogRedactFilter = prevFilter })
+
+	t.Run("capture", func(t *testing.T) {
+		logs := CaptureLogs(t, a)
+		goboot.Default().Log.Info("default app", goboot.Fields{"password": "hunter2"})
+		a.Log.Info("other app", goboot.Fields{"token": "abc"})
+
+		entries := logs.Entries()
+		if len(entries) != 2 || !strings.HasPrefix(entries[0].Message, "default app") || !strings.HasPrefix(entries[1].Message, "other app") {
+			t.Fatalf("entries %+v", entries)
+		}
+		if entries[0].Fields["password"] != "******" || entries[1].Fields["token"] != "******" || strings.Contains(entries[0].Message, "hunter2") {
+			t.Errorf("secrets not redacted: %+v", entries)
+		}
+	})
+
+	if a.Log != prevLog {
+		t.Error("app logger not restored")
+	}
+}
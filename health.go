@@ -0,0 +1,193 @@
+package goboot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHealthCheckTimeout bounds a health check without a Timeout.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthCheckOptions control how a health check is run and reported.
+type HealthCheckOptions struct {
+	Critical bool          // a failure makes /healthz and /readyz return 503
+	Interval time.Duration // the result is cached for Interval, 0 runs the check on every request
+	Timeout  time.Duration // defaults to DefaultHealthCheckTimeout
+}
+
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+	opts  HealthCheckOptions
+
+	mu       sync.Mutex
+	checked  time.Time
+	err      error
+	duration time.Duration
+}
+
+// HealthStatus is the result of one health check.
+type HealthStatus struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// HealthReport is the JSON body of /healthz and /readyz.
+type HealthReport struct {
+	Status string                  `json:"status"`
+	Ready  bool                    `json:"ready"`
+	Checks map[string]HealthStatus `json:"checks,omitempty"`
+}
+
+// RegisterHealthCheck adds or replaces the named check, e.g.
+//
+//	g.RegisterHealthCheck("redis", func(ctx context.Context) error {
+//		return r.Ping().Err()
+//	}, g.HealthCheckOptions{Critical: true, Interval: 10 * time.Second})
+func RegisterHealthCheck(name string, check func(ctx context.Context) error, opts HealthCheckOptions) {
+	defaultApp.RegisterHealthCheck(name, check, opts)
+}
+
+// Ready reports whether the startup hooks completed and shutdown has not
+// begun.
+func Ready() bool {
+	return defaultApp.Ready()
+}
+
+// CheckHealth runs every registered check concurrently. The report status is
+// "fail" when a critical check failed.
+func CheckHealth(ctx context.Context) HealthReport {
+	return defaultApp.CheckHealth(ctx)
+}
+
+// RegisterHealthHandlers serves /healthz, /readyz and /livez on mux.
+func RegisterHealthHandlers(mux *http.ServeMux) {
+	defaultApp.RegisterHealthHandlers(mux)
+}
+
+func (a *App) RegisterHealthCheck(name string, check func(ctx context.Context) error, opts HealthCheckOptions) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultHealthCheckTimeout
+	}
+	a.healthMu.Lock()
+	a.healthChecks[name] = &healthCheck{name: name, check: check, opts: opts}
+	a.healthMu.Unlock()
+}
+
+func (a *App) Ready() bool {
+	return atomic.LoadInt32(&a.ready) == 1
+}
+
+func (a *App) setReady(b bool) {
+	var v int32
+	if b {
+		v = 1
+	}
+	atomic.StoreInt32(&a.ready, v)
+}
+
+// run returns the cached result or runs the check.
+func (hc *healthCheck) run(ctx context.Context) HealthStatus {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if hc.checked.IsZero() || hc.opts.Interval <= 0 || time.Since(hc.checked) >= hc.opts.Interval {
+		ctx, cancel := context.WithTimeout(ctx, hc.opts.Timeout)
+		defer cancel()
+
+		start := time.Now()
+		done := make(chan error, 1)
+		go func() {
+			done <- hc.check(ctx)
+		}()
+		select {
+		case hc.err = <-done:
+		case <-ctx.Done():
+			hc.err = ctx.Err()
+		}
+		hc.duration = time.Since(start)
+		hc.checked = time.Now()
+	}
+
+	st := HealthStatus{Status: "ok", Critical: hc.opts.Critical, Duration: hc.duration.String()}
+	if hc.err != nil {
+		st.Status = "fail"
+		st.Error = hc.err.Error()
+	}
+	return st
+}
+
+func (a *App) CheckHealth(ctx context.Context) HealthReport {
+	a.healthMu.RLock()
+	checks := make([]*healthCheck, 0, len(a.healthChecks))
+	for _, hc := range a.healthChecks {
+		checks = append(checks, hc)
+	}
+	a.healthMu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	results := make([]HealthStatus, len(checks))
+	var wg sync.WaitGroup
+	for i, hc := range checks {
+		wg.Add(1)
+		go func(i int, hc *healthCheck) {
+			defer wg.Done()
+			results[i] = hc.run(ctx)
+		}(i, hc)
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: "ok", Ready: a.Ready(), Checks: make(map[string]HealthStatus, len(checks))}
+	for i, hc := range checks {
+		report.Checks[hc.name] = results[i]
+		if results[i].Critical && results[i].Status != "ok" {
+			report.Status = "fail"
+		}
+	}
+	return report
+}
+
+func writeHealth(w http.ResponseWriter, ok bool, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(v)
+}
+
+func (a *App) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	report := a.CheckHealth(r.Context())
+	writeHealth(w, report.Status == "ok", report)
+}
+
+// readyzHandler fails while the app is starting or shutting down, the checks
+// are only run once it is ready.
+func (a *App) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.Ready() {
+		writeHealth(w, false, HealthReport{Status: "fail"})
+		return
+	}
+	report := a.CheckHealth(r.Context())
+	writeHealth(w, report.Status == "ok", report)
+}
+
+// livezHandler only reports that the process is serving requests.
+func (a *App) livezHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, true, HealthReport{Status: "ok", Ready: a.Ready()})
+}
+
+func (a *App) RegisterHealthHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", a.healthzHandler)
+	mux.HandleFunc("/readyz", a.readyzHandler)
+	mux.HandleFunc("/livez", a.livezHandler)
+}
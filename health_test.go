@@ -0,0 +1,65 @@
+package goboot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthHandlers(t *testing.T) {
+	a := newTestApp(t)
+
+	var calls int32
+	a.RegisterHealthCheck("redis", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}, HealthCheckOptions{Critical: true, Interval: time.Minute})
+	a.RegisterHealthCheck("mail", func(ctx context.Context) error {
+		return errors.New("smtp down")
+	}, HealthCheckOptions{})
+
+	mux := http.NewServeMux()
+	a.RegisterHealthHandlers(mux)
+	get := func(path string) (int, HealthReport) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		var report HealthReport
+		json.NewDecoder(rec.Body).Decode(&report)
+		return rec.Code, report
+	}
+
+	a.setReady(false)
+	if code, _ := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Error("readyz before startup", code)
+	}
+	if code, _ := get("/livez"); code != http.StatusOK {
+		t.Error("livez", code)
+	}
+
+	a.setReady(true)
+	code, report := get("/readyz")
+	if code != http.StatusOK || !report.Ready {
+		t.Error("readyz after startup", code, report)
+	}
+	if report.Checks["mail"].Error != "smtp down" {
+		t.Error("non-critical failure not reported", report)
+	}
+
+	get("/healthz")
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("redis checked %d times, want 1 (cached)", n)
+	}
+
+	a.RegisterHealthCheck("db", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, HealthCheckOptions{Critical: true, Timeout: 10 * time.Millisecond})
+	if code, report := get("/healthz"); code != http.StatusServiceUnavailable || report.Checks["db"].Status != "fail" {
+		t.Error("critical timeout", code, report)
+	}
+}
@@ -0,0 +1,355 @@
+package goboot
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/e2u/goboot/metrics"
+)
+
+// Defaults for the httpclient.<name>.* keys.
+const (
+	DefaultHTTPClientTimeout        = 30 * time.Second
+	DefaultHTTPClientConnectTimeout = 10 * time.Second
+	DefaultHTTPClientRetries        = 2
+	DefaultHTTPClientRetryWait      = 100 * time.Millisecond
+	DefaultHTTPClientRetryMaxWait   = 2 * time.Second
+	DefaultHTTPClientBreakerCount   = 5
+	DefaultHTTPClientBreakerTimeout = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without sending the request while the circuit
+// breaker of a client is open.
+var ErrCircuitOpen = errors.New("httpclient: circuit breaker open")
+
+var (
+	clientRequests = metrics.NewCounter("goboot_httpclient_requests_total", "Outbound HTTP requests, by client and status code.", "client", "code")
+	clientDuration = metrics.NewHistogram("goboot_httpclient_request_duration_seconds", "Outbound HTTP request duration including retries, by client.", nil, "client")
+)
+
+// HTTPClient returns the named client of the default App, see
+// App.HTTPClient.
+func HTTPClient(name string) (*http.Client, error) {
+	return defaultApp.HTTPClient(name)
+}
+
+// HTTPClient returns the client configured by the httpclient.<name>.* keys,
+// the same client is returned for every call with the same name:
+//
+//	httpclient.<name>.timeout                   overall timeout of a request, including retries
+//	httpclient.<name>.timeout.connect           dial timeout
+//	httpclient.<name>.timeout.tls_handshake     TLS handshake timeout
+//	httpclient.<name>.timeout.response_header   time to wait for the response headers
+//	httpclient.<name>.timeout.idle              how long idle connections are kept
+//	httpclient.<name>.proxy                     proxy URL, "off" for none, default from the environment
+//	httpclient.<name>.tls.ca                    PEM file of the CAs to trust instead of the system pool
+//	httpclient.<name>.tls.cert, tls.key         client certificate
+//	httpclient.<name>.tls.insecure              skip server certificate verification
+//	httpclient.<name>.max_idle_conns            idle connections kept, in total
+//	httpclient.<name>.max_idle_conns_per_host   idle connections kept per host
+//	httpclient.<name>.max_conns_per_host        connections per host, 0 is unlimited
+//	httpclient.<name>.retry.max                 retries of idempotent requests, 0 disables
+//	httpclient.<name>.retry.wait                first backoff, doubled for each retry
+//	httpclient.<name>.retry.max_wait            backoff limit
+//	httpclient.<name>.breaker.failures          consecutive failures opening the breaker, 0 disables
+//	httpclient.<name>.breaker.timeout           how long the breaker stays open
+//
+// Requests with an idempotent method, or an Idempotency-Key header, are
+// retried on connection errors and on 429, 502, 503 and 504 responses with
+// exponential backoff and jitter. Every request is logged through Log, and
+// dumped as enabled by the log.dump.http.* keys.
+func (a *App) HTTPClient(name string) (*http.Client, error) {
+	a.httpClientsMu.Lock()
+	defer a.httpClientsMu.Unlock()
+	if c, ok := a.httpClients[name]; ok {
+		return c, nil
+	}
+
+	c, err := a.newHTTPClient(name)
+	if err != nil {
+		return nil, fmt.Errorf("httpclient %s: %v", name, err)
+	}
+	if a.httpClients == nil {
+		a.httpClients = make(map[string]*http.Client)
+	}
+	a.httpClients[name] = c
+	return c, nil
+}
+
+func (a *App) newHTTPClient(name string) (*http.Client, error) {
+	cfg := a.Config
+	if cfg == nil {
+		cfg = NewConfigWithoutFile(a.runMode)
+	}
+	key := func(k string) string { return "httpclient." + name + "." + k }
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.MustDuration(key("timeout.connect"), DefaultHTTPClientConnectTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.MustDuration(key("timeout.tls_handshake"), 10*time.Second),
+		ResponseHeaderTimeout: cfg.MustDuration(key("timeout.response_header")),
+		IdleConnTimeout:       cfg.MustDuration(key("timeout.idle"), 90*time.Second),
+		MaxIdleConns:          cfg.MustInt(key("max_idle_conns"), 100),
+		MaxIdleConnsPerHost:   cfg.MustInt(key("max_idle_conns_per_host"), http.DefaultMaxIdleConnsPerHost),
+		MaxConnsPerHost:       cfg.MustInt(key("max_conns_per_host")),
+		ForceAttemptHTTP2:     true,
+	}
+
+	switch proxy := cfg.MustString(key("proxy")); proxy {
+	case "":
+	case "off":
+		transport.Proxy = nil
+	default:
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	tlsConfig, err := clientTLSConfig(cfg, key)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	rt := &clientTransport{
+		app:       a,
+		name:      name,
+		next:      a.DumpTransport(transport),
+		retries:   cfg.MustInt(key("retry.max"), DefaultHTTPClientRetries),
+		wait:      cfg.MustDuration(key("retry.wait"), DefaultHTTPClientRetryWait),
+		maxWait:   cfg.MustDuration(key("retry.max_wait"), DefaultHTTPClientRetryMaxWait),
+		threshold: cfg.MustInt(key("breaker.failures"), DefaultHTTPClientBreakerCount),
+		cooldown:  cfg.MustDuration(key("breaker.timeout"), DefaultHTTPClientBreakerTimeout),
+	}
+	a.OnAppStop(func() error {
+		transport.CloseIdleConnections()
+		return nil
+	})
+	return &http.Client{
+		Transport: rt,
+		Timeout:   cfg.MustDuration(key("timeout"), DefaultHTTPClientTimeout),
+	}, nil
+}
+
+func clientTLSConfig(cfg *ConfigContext, key func(string) string) (*tls.Config, error) {
+	c := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.MustBool(key("tls.insecure")),
+	}
+	if ca := cfg.MustString(key("tls.ca")); ca != "" {
+		pem, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("tls.ca: %v", err)
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls.ca: no certificate in %s", ca)
+		}
+	}
+	if cert := cfg.MustString(key("tls.cert")); cert != "" {
+		pair, err := tls.LoadX509KeyPair(cert, cfg.MustString(key("tls.key")))
+		if err != nil {
+			return nil, fmt.Errorf("tls.cert: %v", err)
+		}
+		c.Certificates = []tls.Certificate{pair}
+	}
+	return c, nil
+}
+
+// clientTransport retries idempotent requests, trips a circuit breaker on
+// consecutive failures and logs every request.
+type clientTransport struct {
+	app  *App
+	name string
+	next http.RoundTripper
+
+	retries       int
+	wait, maxWait time.Duration
+
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (t *clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	defer clientDuration.ObserveSince(start, t.name)
+
+	retries := 0
+	if retryable(req) {
+		retries = t.retries
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.allow(); err != nil {
+			clientRequests.Inc(t.name, "breaker")
+			t.app.Log.Warningf("httpclient %s: %s %s: %v", t.name, req.Method, req.URL, err)
+			return nil, err
+		}
+
+		r := req
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		resp, err := t.next.RoundTrip(r)
+		failed := err != nil || resp.StatusCode >= 500
+		t.record(failed)
+
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		clientRequests.Inc(t.name, code)
+
+		if attempt >= retries || !retryStatus(resp, err) || req.Context().Err() != nil {
+			t.logResult(req, resp, err, attempt, start)
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		t.app.Log.Warningf("httpclient %s: %s %s: %s, retrying in %s", t.name, req.Method, req.URL, describe(resp, err), wait)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *clientTransport) logResult(req *http.Request, resp *http.Response, err error, attempt int, start time.Time) {
+	msg := fmt.Sprintf("httpclient %s: %s %s: %s (%s", t.name, req.Method, req.URL, describe(resp, err), time.Since(start))
+	if attempt > 0 {
+		msg += fmt.Sprintf(", %d retries", attempt)
+	}
+	msg += ")"
+	if err != nil {
+		t.app.Log.Error(msg)
+	} else {
+		t.app.Log.Info(msg)
+	}
+}
+
+func describe(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
+
+// retryable reports whether req can be sent again: its method is idempotent
+// or it carries an Idempotency-Key, and its body can be replayed.
+func retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func retryStatus(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before the next attempt: a random duration
+// between half and all of wait*2^attempt, capped at maxWait, or the
+// Retry-After of the response when it is shorter than maxWait.
+func (t *clientTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			if d := time.Duration(s) * time.Second; d <= t.maxWait {
+				return d
+			}
+		}
+	}
+
+	d := t.wait << uint(attempt)
+	if d > t.maxWait || d <= 0 {
+		d = t.maxWait
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// allow fails while the breaker is open. Once the timeout expired a single
+// request probes the server, its result closes or reopens the breaker.
+func (t *clientTransport) allow() error {
+	if t.threshold <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failures < t.threshold {
+		return nil
+	}
+	if time.Now().Before(t.openUntil) || t.probing {
+		return ErrCircuitOpen
+	}
+	t.probing = true
+	return nil
+}
+
+func (t *clientTransport) record(failed bool) {
+	if t.threshold <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.probing = false
+	if !failed {
+		t.failures = 0
+		return
+	}
+	if t.failures++; t.failures >= t.threshold {
+		if t.failures == t.threshold {
+			t.app.Log.Errorf("httpclient %s: %d consecutive failures, circuit breaker open for %s", t.name, t.failures, t.cooldown)
+		}
+		t.openUntil = time.Now().Add(t.cooldown)
+	}
+}
@@ -0,0 +1,119 @@
+package goboot
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func httpClientApp(t *testing.T, keys map[string]string) *App {
+	a := newTestApp(t)
+	for k, v := range keys {
+		a.Config.RunModeSection.NewKey("httpclient.api."+k, v)
+	}
+	return a
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	a := httpClientApp(t, map[string]string{"retry.max": "3", "retry.wait": "1ms"})
+	c, err := a.HTTPClient("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := a.HTTPClient("api"); again != c {
+		t.Error("client not reused")
+	}
+
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || calls != 3 {
+		t.Errorf("status %d after %d calls", resp.StatusCode, calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	resp, err = c.Post(srv.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls != 1 {
+		t.Errorf("POST retried: status %d after %d calls", resp.StatusCode, calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("x"))
+	req.Header.Set("Idempotency-Key", "k1")
+	if resp, err = c.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || calls != 3 {
+		t.Errorf("POST with Idempotency-Key: status %d after %d calls", resp.StatusCode, calls)
+	}
+}
+
+func TestHTTPClientBreaker(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	a := httpClientApp(t, map[string]string{"retry.max": "0", "breaker.failures": "2", "breaker.timeout": "1h"})
+	c, _ := a.HTTPClient("api")
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := c.Get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Error("breaker not open:", err)
+	}
+	if calls != 2 {
+		t.Errorf("%d calls reached the server", calls)
+	}
+}
+
+func TestHTTPClientTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600)
+
+	c, err := httpClientApp(t, map[string]string{"tls.ca": ca}).HTTPClient("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, err := httpClientApp(t, map[string]string{"tls.ca": ca + ".missing"}).HTTPClient("api"); err == nil {
+		t.Error("missing CA file accepted")
+	}
+}
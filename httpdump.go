@@ -0,0 +1,257 @@
+package goboot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultDumpBodyMax caps the dumped bodies when log.dump.http.body.max is not
+// set.
+const DefaultDumpBodyMax = 4096
+
+// DumpHandler dumps the requests and responses of next with the default App,
+// see App.DumpHandler.
+func DumpHandler(next http.Handler) http.Handler {
+	return defaultApp.DumpHandler(next)
+}
+
+// DumpTransport dumps the requests and responses of next with the default App,
+// see App.DumpTransport.
+func DumpTransport(next http.RoundTripper) http.RoundTripper {
+	return defaultApp.DumpTransport(next)
+}
+
+// DumpHandler returns middleware writing the requests received and the
+// responses sent to HTTPLog, as enabled by the log.dump.http.* keys at the
+// time of each request. Bodies are cut after log.dump.http.body.max bytes,
+// headers matching the redaction keys are masked.
+func (a *App) DumpHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := a.Config
+		if c == nil || !c.LogDumpHttpRequest() && !c.LogDumpHttpResponse() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		max := c.LogDumpHttpBodyMax()
+
+		if c.LogDumpHttpRequest() {
+			var body []byte
+			var truncated bool
+			if c.LogDumpHttpRequestBody() {
+				body, truncated, r.Body = peekBody(r.Body, max)
+			}
+			header := r.Header.Clone()
+			header.Set("Host", r.Host)
+			a.HTTPLog().Info(a.dumpMessage("http request", fmt.Sprintf("%s %s %s", r.Method, r.URL.RequestURI(), r.Proto), header, body, truncated))
+		}
+		if !c.LogDumpHttpResponse() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := &responseWriter{ResponseWriter: w}
+		if c.LogDumpHttpResponseBody() {
+			rw.capture = max
+		}
+		start := time.Now()
+		next.ServeHTTP(rw, r)
+
+		title := fmt.Sprintf("http response %s %s (%s)", r.Method, r.URL.Path, time.Since(start))
+		status := fmt.Sprintf("%s %d %s", r.Proto, rw.Status(), http.StatusText(rw.Status()))
+		a.HTTPLog().Info(a.dumpMessage(title, status, w.Header(), rw.body.Bytes(), rw.size > int64(rw.body.Len())))
+	})
+}
+
+// DumpTransport returns a RoundTripper dumping the requests sent by next and
+// the responses received like DumpHandler. A nil next is
+// http.DefaultTransport.
+func (a *App) DumpTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &dumpTransport{app: a, next: next}
+}
+
+type dumpTransport struct {
+	app  *App
+	next http.RoundTripper
+}
+
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	a, c := t.app, t.app.Config
+	if c == nil || !c.LogDumpHttpRequest() && !c.LogDumpHttpResponse() {
+		return t.next.RoundTrip(req)
+	}
+	max := c.LogDumpHttpBodyMax()
+
+	if c.LogDumpHttpRequest() {
+		var body []byte
+		var truncated bool
+		if c.LogDumpHttpRequestBody() && req.Body != nil && req.Body != http.NoBody {
+			// A RoundTripper must not modify the request it was given.
+			req = req.Clone(req.Context())
+			body, truncated, req.Body = peekBody(req.Body, max)
+		}
+		a.HTTPLog().Info(a.dumpMessage("http client request", fmt.Sprintf("%s %s %s", req.Method, req.URL, req.Proto), req.Header, body, truncated))
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if !c.LogDumpHttpResponse() {
+		return resp, err
+	}
+	if err != nil {
+		a.HTTPLog().Infof("http client response %s %s (%s): %v", req.Method, req.URL, time.Since(start), err)
+		return resp, err
+	}
+
+	var body []byte
+	var truncated bool
+	if c.LogDumpHttpResponseBody() {
+		body, truncated, resp.Body = peekBody(resp.Body, max)
+	}
+	title := fmt.Sprintf("http client response %s %s (%s)", req.Method, req.URL, time.Since(start))
+	a.HTTPLog().Info(a.dumpMessage(title, resp.Proto+" "+resp.Status, resp.Header, body, truncated))
+	return resp, nil
+}
+
+// dumpMessage formats a request or response as one log message.
+func (a *App) dumpMessage(title, first string, header http.Header, body []byte, truncated bool) string {
+	var b strings.Builder
+	b.WriteString(title)
+	b.WriteString("\n")
+	b.WriteString(first)
+	b.WriteString("\n")
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range header[name] {
+			if a.LogRedactFilter != nil && a.LogRedactFilter.MatchKey(name) {
+				v = a.LogRedactFilter.Mask
+			}
+			fmt.Fprintf(&b, "%s: %s\n", name, v)
+		}
+	}
+
+	if len(body) > 0 {
+		b.WriteString("\n")
+		b.Write(body)
+		if truncated {
+			fmt.Fprintf(&b, "\n... (truncated after %d bytes)", len(body))
+		}
+	}
+	return b.String()
+}
+
+// peekBody reads up to max bytes of rc and returns them with a reader
+// replaying the whole body. truncated reports whether the body is longer.
+func peekBody(rc io.ReadCloser, max int) (body []byte, truncated bool, replay io.ReadCloser) {
+	if rc == nil || rc == http.NoBody {
+		return nil, false, rc
+	}
+
+	buf := make([]byte, max+1)
+	n, err := io.ReadFull(rc, buf)
+	buf = buf[:n]
+
+	var rest io.Reader = rc
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		rest = errReader{err}
+	}
+	replay = readCloser{io.MultiReader(bytes.NewReader(buf), rest), rc}
+
+	if n > max {
+		return buf[:max], true, replay
+	}
+	return buf, false, replay
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// responseWriter records the status and size of a response and keeps the
+// first capture bytes of its body.
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int64
+	capture int
+	body    bytes.Buffer
+}
+
+// Status returns the status code sent, 200 when the handler wrote nothing.
+func (rw *responseWriter) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if n := rw.capture - rw.body.Len(); n > 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		rw.body.Write(b[:n])
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += int64(n)
+	return n, err
+}
+
+// Flush supports streaming handlers when the wrapped writer does.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack supports protocol upgrades, e.g. WebSocket, when the wrapped writer
+// does. The response is then logged with the status 101.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %T is not a http.Hijacker", http.ErrNotSupported, rw.ResponseWriter)
+	}
+	conn, brw, err := h.Hijack()
+	if err == nil && rw.status == 0 {
+		rw.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
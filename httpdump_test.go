@@ -0,0 +1,135 @@
+package goboot
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logging "github.com/op/go-logging"
+)
+
+// newDumpTestApp returns an App dumping to mem.
+func newDumpTestApp(t *testing.T) (*App, *logging.MemoryBackend) {
+	a := newTestApp(t)
+	enableDumps(a)
+	mem := logging.NewMemoryBackend(16)
+	l := logging.MustGetLogger("http")
+	l.SetBackend(logging.AddModuleLevel(mem))
+	a.httpLogOnce.Do(func() { a.httpLog = l })
+	return a, mem
+}
+
+func enableDumps(a *App) {
+	a.LogRedactFilter, _ = NewRedactFilter(DefaultRedactKeys, nil)
+	a.Config.SetLogDumpHttpRequest(true)
+	a.Config.SetLogDumpHttpRequestBody(true)
+	a.Config.SetLogDumpHttpResponse(true)
+	a.Config.SetLogDumpHttpResponseBody(true)
+	a.Config.RunModeSection.NewKey(IniDumpHttpBodyMax, "8")
+}
+
+func dumped(mem *logging.MemoryBackend) []string {
+	var msgs []string
+	for n := mem.Head(); n != nil; n = n.Next() {
+		msgs = append(msgs, n.Record.Message())
+	}
+	return msgs
+}
+
+func TestDumpHandler(t *testing.T) {
+	a, mem := newDumpTestApp(t)
+	h := a.DumpHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(b)
+	}))
+
+	r := httptest.NewRequest("POST", "/users?page=2", strings.NewReader("name=rob&city=paris"))
+	r.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	if rec.Body.String() != "name=rob&city=paris" {
+		t.Errorf("handler read %q", rec.Body)
+	}
+	msgs := dumped(mem)
+	if len(msgs) != 2 {
+		t.Fatalf("%d dumps: %q", len(msgs), msgs)
+	}
+	for _, want := range []string{"POST /users?page=2 HTTP/1.1", "Authorization: ******", "\nname=rob", "truncated after 8 bytes"} {
+		if !strings.Contains(msgs[0], want) {
+			t.Errorf("request dump misses %q:\n%s", want, msgs[0])
+		}
+	}
+	if strings.Contains(msgs[0], "s3cret") || strings.Contains(msgs[0], "paris") {
+		t.Errorf("request dump leaks:\n%s", msgs[0])
+	}
+	if !strings.Contains(msgs[1], "HTTP/1.1 201 Created") || !strings.Contains(msgs[1], "\nname=rob") {
+		t.Errorf("response dump:\n%s", msgs[1])
+	}
+}
+
+func TestDumpTransport(t *testing.T) {
+	a, mem := newDumpTestApp(t)
+	a.Config.SetLogDumpHttpResponseBody(false)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: a.DumpTransport(nil)}
+	resp, err := client.Post(srv.URL+"/echo", "text/plain", strings.NewReader("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "0123456789abcdef" {
+		t.Errorf("server received %q", b)
+	}
+
+	msgs := dumped(mem)
+	if len(msgs) != 2 || !strings.Contains(msgs[0], "\n01234567\n") || !strings.Contains(msgs[1], "200 OK") || strings.Contains(msgs[1], "0123") {
+		t.Errorf("dumps: %q", msgs)
+	}
+}
+
+func TestDumpOutput(t *testing.T) {
+	a := newTestApp(t)
+	enableDumps(a)
+	mem := logging.NewMemoryBackend(16)
+	a.SetLogBackend(logging.AddModuleLevel(mem))
+	out := filepath.Join(t.TempDir(), "http.log")
+	a.Config.RunModeSection.NewKey(IniHttpLogOutput, out)
+	a.Config.RunModeSection.NewKey(IniHttpLogFormat, AccessLogJSON)
+
+	h := a.AccessLog(a.DumpHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ping", nil))
+
+	b, _ := os.ReadFile(out)
+	if n := strings.Count(string(b), `{"timestamp":`); n != 2 || !strings.Contains(string(b), `"path":"/ping"`) {
+		t.Errorf("http.log.output %q", b)
+	}
+	if msgs := dumped(mem); len(msgs) != 0 {
+		t.Errorf("dumps written to Log: %q", msgs)
+	}
+}
+
+func TestDumpWithoutOutput(t *testing.T) {
+	a := newTestApp(t)
+	enableDumps(a)
+	mem := logging.NewMemoryBackend(16)
+	a.SetLogBackend(logging.AddModuleLevel(mem))
+
+	a.DumpHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ping", nil))
+	for _, msg := range dumped(mem) {
+		if strings.Contains(msg, "/ping") {
+			t.Errorf("dump written to Log: %q", msg)
+		}
+	}
+}
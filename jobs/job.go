@@ -27,7 +27,9 @@ import (
 	"runtime/debug"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/e2u/goboot/metrics"
 	"gopkg.in/robfig/cron.v2"
 )
 
@@ -43,6 +45,10 @@ var (
 	JobPoolSize    int
 	MainCron       *cron.Cron
 	workPermits    chan struct{}
+
+	jobRuns     = metrics.NewCounter("goboot_job_runs_total", "Job runs, by job name.", "job")
+	jobPanics   = metrics.NewCounter("goboot_job_panics_total", "Job runs ended by a panic, by job name.", "job")
+	jobDuration = metrics.NewHistogram("goboot_job_duration_seconds", "Job run duration, by job name.", nil, "job")
 )
 
 const UNNAMED = "(unnamed)"
@@ -80,6 +86,7 @@ func (j *Job) Run() {
 	// Don't let the whole process die.
 	defer func() {
 		if err := recover(); err != nil {
+			jobPanics.Inc(j.Name)
 			log.Print(err, "\n", string(debug.Stack()))
 		}
 	}()
@@ -107,5 +114,8 @@ func (j *Job) Run() {
 	atomic.StoreUint32(&j.status, 1)
 	defer atomic.StoreUint32(&j.status, 0)
 
+	jobRuns.Inc(j.Name)
+	defer jobDuration.ObserveSince(time.Now(), j.Name)
+
 	j.inner.Run()
 }
@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/robfig/cron.v2"
+)
+
+// Scheduler runs jobs on its own cron instance, the package level functions
+// use MainCron.
+type Scheduler struct {
+	Cron *cron.Cron
+
+	mu      sync.Mutex
+	running bool
+}
+
+// NewScheduler returns a scheduler that runs nothing until Start.
+func NewScheduler() *Scheduler {
+	return &Scheduler{Cron: cron.New()}
+}
+
+// MainScheduler wraps MainCron, which is started by the package init.
+func MainScheduler() *Scheduler {
+	return &Scheduler{Cron: MainCron, running: true}
+}
+
+// Start runs the scheduler, starting a running scheduler does nothing.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		s.Cron.Start()
+		s.running = true
+	}
+}
+
+// Stop stops scheduling new runs, jobs already running are not interrupted.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.Cron.Stop()
+		s.running = false
+	}
+}
+
+// Schedule runs the given job on a cron spec, see Schedule.
+func (s *Scheduler) Schedule(spec string, job cron.Job) error {
+	sched, err := cron.Parse(spec)
+	if err != nil {
+		return err
+	}
+	s.Cron.Schedule(sched, New(job))
+	return nil
+}
+
+// Every runs the given job at a fixed interval, see Every.
+func (s *Scheduler) Every(duration time.Duration, job cron.Job) {
+	s.Cron.Schedule(cron.Every(duration), New(job))
+}
@@ -1,13 +1,45 @@
 package goboot
 
 import (
+	"fmt"
+	"io"
 	"os"
 
+	"github.com/e2u/goboot/metrics"
 	logging "github.com/op/go-logging"
 )
 
+// Logger is the logging facade used by goboot and exposed as Log.
+// *logging.Logger satisfies it, NewSlogLogger adapts a *slog.Logger.
+// Fatal logs then exits with status 1, Panic logs then panics.
+type Logger interface {
+	Fatal(args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Panic(args ...interface{})
+	Panicf(format string, args ...interface{})
+	Critical(args ...interface{})
+	Criticalf(format string, args ...interface{})
+	Error(args ...interface{})
+	Errorf(format string, args ...interface{})
+	Warning(args ...interface{})
+	Warningf(format string, args ...interface{})
+	Notice(args ...interface{})
+	Noticef(format string, args ...interface{})
+	Info(args ...interface{})
+	Infof(format string, args ...interface{})
+	Debug(args ...interface{})
+	Debugf(format string, args ...interface{})
+}
+
 var (
-	Log                       *logging.Logger
+	Log Logger
+
+	// LogRedactFilter masks secrets before records reach any backend,
+	// it is nil when log.redact is false.
+	LogRedactFilter *RedactFilter
+
+	logMessages = metrics.NewCounter("goboot_log_messages_total", "Log records written, by level.", "level")
+
 	LoggingFormatWithColor    = logging.MustStringFormatter(`%{color}%{time:2006-01-02T15:04:05.9999-07:00} %{id:08x} %{shortfile} %{longfunc} ▶ %{level:-8s} %{color:reset} %{message}`)
 	LoggingFormatJSON         = logging.MustStringFormatter(`{"timestamp":"%{time:2006-01-02T15:04:05.9999-07:00}","id":%{id:08x},"filename":"%{shortfile}","func":"%{longfunc}","level":"%{level:s}","msg":"%{message}"}`)
 	LoggingFormatWithoutColor = logging.MustStringFormatter(`%{time:2006-01-02T15:04:05.9999-07:00} %{id:08x} %{shortfile} %{longfunc} ▶ %{level:-8s} %{message}`)
@@ -20,63 +52,230 @@ func (eb EmtpyBackend) Log(level logging.Level, calldepth int, rec *logging.Reco
 	return nil
 }
 
+// nopLog discards its records, see defaultLog.
+var nopLog = func() *logging.Logger {
+	l := logging.MustGetLogger("goboot")
+	l.SetBackend(logging.AddModuleLevel(EmtpyBackend{}))
+	return l
+}()
+
+// defaultLog returns Log, or a logger discarding its records before Log is
+// set, e.g. when only Apps made by New are used.
+func defaultLog() Logger {
+	if Log != nil {
+		return Log
+	}
+	return nopLog
+}
+
 func InitLogger() {
-	module := Config.MustString("app.name", "unknown")
-	InitLoggerWithModule(module)
+	syncDefault().InitLogger()
+	Log, LogRedactFilter = defaultApp.Log, defaultApp.LogRedactFilter
 }
 
 func InitLoggerWithModule(module string) {
-	format := Config.MustString(IniLogFormat, "plain")
-	level := Config.MustString(IniLevel, "DEBUG")
-	output := Config.MustString(IniLogOutput, "stdout")
+	syncDefault().InitLoggerWithModule(module)
+	Log, LogRedactFilter = defaultApp.Log, defaultApp.LogRedactFilter
+}
+
+// InitLogger builds Log from the log.* keys of the App configuration, the
+// module is app.name.
+func (a *App) InitLogger() {
+	a.InitLoggerWithModule(a.Config.MustString("app.name", "unknown"))
+}
+
+func (a *App) InitLoggerWithModule(module string) {
+	format := a.Config.MustString(IniLogFormat, "plain")
+	level := a.Config.MustString(IniLevel, "DEBUG")
+	output := a.Config.MustString(IniLogOutput, "stdout")
+	pattern := a.Config.MustString(IniLogFormatPattern, "")
 
-	Log = initLogger(module, format, level, output)
+	a.LogRedactFilter = NewRedactFilterWithConfig(a.Config)
+	a.Log = a.initLogger(module, format, pattern, level, output, a.LogRedactFilter)
 }
 
-func initLogger(module string, format, level, output string) *logging.Logger {
+func (a *App) initLogger(module string, format, pattern, level, output string, filter *RedactFilter) *logging.Logger {
 	l := logging.MustGetLogger(module)
+	a.Log = l
+	a.SetLogBackend(newLogBackend(module, format, pattern, level, getWriter(output), filter))
+	return l
+}
+
+// newLogBackend builds the backend chain writing records of module to w.
+func newLogBackend(module string, format, pattern, level string, w io.Writer, filter *RedactFilter) logging.LeveledBackend {
+	b := getBackend(w)
+	formater := logging.NewBackendFormatter(b, getFormatter(format, pattern, w))
+	backendLeveled := logging.AddModuleLevel(countingBackend{NewRedactBackend(formater, filter)})
+	lev, err := logging.LogLevel(level)
 
-	var loggingFormat logging.Formatter
+	if err != nil {
+		lev = logging.DEBUG
+	}
+	backendLeveled.SetLevel(lev, module)
+	return backendLeveled
+}
+
+// HTTPLog returns the logger of the default App for HTTP dumps, see
+// App.HTTPLog.
+func HTTPLog() Logger {
+	return defaultApp.HTTPLog()
+}
+
+// HTTPLog returns the logger for HTTP dumps. It writes to http.log.output,
+// next to the access log, as JSON when http.log.format is "json" and plain
+// otherwise, with the same redaction as Log. Without http.log.output the
+// dumps are discarded, they never go to Log.
+func (a *App) HTTPLog() Logger {
+	a.httpLogOnce.Do(a.initHTTPLog)
+	if a.httpLog == nil {
+		return nopLog
+	}
+	return a.httpLog
+}
+
+// initHTTPLog opens http.log.output, shared by the access log and HTTPLog.
+// The dumps are written as JSON when http.log.format is "json", else plain.
+func (a *App) initHTTPLog() {
+	if a.Config == nil {
+		return
+	}
+	output := a.Config.MustString(IniHttpLogOutput)
+	if output == "" {
+		if a.Log != nil {
+			a.Log.Infof("%s is not set, the access log and the HTTP dumps are disabled", IniHttpLogOutput)
+		}
+		return
+	}
+	a.httpLogWriter = getWriter(output)
+	format := "plain"
+	if a.Config.MustString(IniHttpLogFormat) == AccessLogJSON {
+		format = "json"
+	}
+	l := logging.MustGetLogger("http")
+	l.SetBackend(newLogBackend("http", format, "", "DEBUG", a.httpLogWriter, a.LogRedactFilter))
+	a.httpLog = l
+}
+
+// countingBackend counts the records that passed the level filter.
+type countingBackend struct {
+	logging.Backend
+}
+
+func (cb countingBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	logMessages.Inc(level.String())
+	return cb.Backend.Log(level, calldepth+1, rec)
+}
+
+// LogBackend returns the backend Log currently writes to, nil before the
+// logger is initialized.
+func LogBackend() logging.LeveledBackend {
+	return defaultApp.LogBackend()
+}
+
+// SetLogBackend installs b as the backend of Log and returns the previous
+// one. A nil b restores the go-logging defaults.
+func SetLogBackend(b logging.LeveledBackend) logging.LeveledBackend {
+	return defaultApp.SetLogBackend(b)
+}
+
+func (a *App) LogBackend() logging.LeveledBackend {
+	return a.logBackend
+}
+
+// SetLogBackend installs b as the backend of the App logger and returns the
+// previous one. The default App sets the go-logging default backend, other
+// Apps set the backend of their own *logging.Logger.
+func (a *App) SetLogBackend(b logging.LeveledBackend) logging.LeveledBackend {
+	prev := a.logBackend
+	a.logBackend = b
+	if a == defaultApp {
+		if b == nil {
+			logging.Reset()
+		} else {
+			logging.SetBackend(b)
+		}
+		return prev
+	}
+
+	if l, ok := a.Log.(*logging.Logger); ok {
+		if b == nil {
+			a.Log = logging.MustGetLogger(l.Module)
+		} else {
+			l.SetBackend(b)
+		}
+	}
+	return prev
+}
+
+// getFormatter returns the formatter for the log.format value. A non-empty
+// pattern is a custom go-logging format and takes precedence over format;
+// "auto" picks colour only when w is a terminal and NO_COLOR is not set.
+func getFormatter(format, pattern string, w io.Writer) logging.Formatter {
+	if pattern != "" {
+		f, err := logging.NewStringFormatter(pattern)
+		if err == nil {
+			return f
+		}
+		fmt.Fprintf(os.Stderr, "goboot: invalid %s %q: %v\n", IniLogFormatPattern, pattern, err)
+	}
 
 	switch format {
 	case "plain":
-		loggingFormat = LoggingFormatWithoutColor
+		return LoggingFormatWithoutColor
 	case "plain-color":
-		loggingFormat = LoggingFormatWithColor
+		return LoggingFormatWithColor
+	case "auto":
+		if colorEnabled(w) {
+			return LoggingFormatWithColor
+		}
+		return LoggingFormatWithoutColor
 	case "json":
-		loggingFormat = LoggingFormatJSON
+		return LoggingFormatJSON
 	case "logstash":
-		loggingFormat = LoggingFormatLogStash
+		return LoggingFormatLogStash
 	default:
-		loggingFormat = LoggingFormatWithoutColor
+		return LoggingFormatWithoutColor
 	}
+}
 
-	b := getBackend(output)
-	formater := logging.NewBackendFormatter(b, loggingFormat)
-	backendLeveled := logging.AddModuleLevel(formater)
-	lev, err := logging.LogLevel(level)
-
+// colorEnabled reports whether ANSI colours should be written to w.
+// See https://no-color.org for the NO_COLOR convention.
+func colorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
 	if err != nil {
-		lev = logging.DEBUG
+		return false
 	}
-	backendLeveled.SetLevel(lev, module)
-	logging.SetBackend(backendLeveled)
-	return l
+	return fi.Mode()&os.ModeCharDevice != 0
 }
 
-func getBackend(output string) logging.Backend {
+// getWriter opens the log.output destination, returns nil when the output
+// is "off" or can not be opened.
+func getWriter(output string) io.Writer {
 	switch output {
 	case "off":
-		return EmtpyBackend{}
+		return nil
 	case "stdout":
-		return logging.NewLogBackend(os.Stdout, "", 0)
+		return os.Stdout
 	case "stderr":
-		return logging.NewLogBackend(os.Stdout, "", 0)
+		return os.Stderr
 	default:
 		if out, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, os.ModeAppend|0644); err == nil {
-			return logging.NewLogBackend(out, "", 0)
-		} else {
-			return EmtpyBackend{}
+			return out
 		}
+		return nil
+	}
+}
+
+func getBackend(w io.Writer) logging.Backend {
+	if w == nil {
+		return EmtpyBackend{}
 	}
+	return logging.NewLogBackend(w, "", 0)
 }
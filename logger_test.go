@@ -0,0 +1,54 @@
+package goboot
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestGetWriterStderr(t *testing.T) {
+	if getWriter("stderr") != os.Stderr {
+		t.Error("stderr")
+	}
+	if getWriter("stdout") != os.Stdout {
+		t.Error("stdout")
+	}
+	if getWriter("off") != nil {
+		t.Error("off")
+	}
+}
+
+func TestGetFormatterAuto(t *testing.T) {
+	var buf bytes.Buffer
+	if getFormatter("auto", "", &buf) != LoggingFormatWithoutColor {
+		t.Error("auto on non-terminal writer")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if getFormatter("auto", "", w) != LoggingFormatWithoutColor {
+		t.Error("auto on pipe")
+	}
+}
+
+func TestColorEnabledNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	if colorEnabled(os.Stdout) {
+		t.Error("NO_COLOR")
+	}
+}
+
+func TestGetFormatterPattern(t *testing.T) {
+	f := getFormatter("json", "%{level} %{message}", nil)
+	if f == LoggingFormatJSON {
+		t.Error("pattern should take precedence over format")
+	}
+
+	if getFormatter("json", "%{bogus", nil) != LoggingFormatJSON {
+		t.Error("invalid pattern should fall back to format")
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Requests.", "method", "code")
+	g := r.NewGauge("queue_size", "Queue size.")
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+
+	c.Inc("GET", "200")
+	c.Add(2, "GET", "200")
+	c.Inc("POST", `5"0\0`)
+	g.Set(7)
+	g.Dec()
+	h.Observe(0.05, "get")
+	h.Observe(0.5, "get")
+	h.Observe(3, "get")
+
+	var buf bytes.Buffer
+	if err := r.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 1
+latency_seconds_bucket{op="get",le="1"} 2
+latency_seconds_bucket{op="get",le="+Inf"} 3
+latency_seconds_sum{op="get"} 3.55
+latency_seconds_count{op="get"} 3
+# HELP queue_size Queue size.
+# TYPE queue_size gauge
+queue_size 6
+# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{method="GET",code="200"} 3
+requests_total{method="POST",code="5\"0\\0"} 1
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	r := NewRegistry()
+	if r.NewCounter("a", "A.", "x").f != r.NewCounter("a", "A.", "x").f {
+		t.Error("same counter registered twice should be shared")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on type mismatch")
+		}
+	}()
+	r.NewGauge("a", "A.")
+}
+
+func TestRuntimeMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	Default.WritePrometheus(&buf)
+	for _, want := range []string{"\ngo_goroutines ", "# TYPE go_gc_cycles_total counter\n", "# TYPE go_gc_pause_seconds_total counter\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%q not in\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+var startTime = time.Now()
+
+// RegisterRuntimeMetrics adds Go runtime statistics to r, refreshed on every
+// exposition. They are part of the Default registry.
+func RegisterRuntimeMetrics(r *Registry) {
+	goroutines := r.NewGauge("go_goroutines", "Number of goroutines that currently exist.")
+	threads := r.NewGauge("go_threads", "Number of OS threads created.")
+	alloc := r.NewGauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.")
+	sys := r.NewGauge("go_memstats_sys_bytes", "Number of bytes obtained from system.")
+	heapInuse := r.NewGauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.")
+	heapObjects := r.NewGauge("go_memstats_heap_objects", "Number of allocated objects.")
+	gcCycles := r.NewCounter("go_gc_cycles_total", "Number of completed GC cycles.")
+	gcPause := r.NewCounter("go_gc_pause_seconds_total", "Total GC stop-the-world pause time.")
+	uptime := r.NewGauge("process_uptime_seconds", "Seconds since the process started.")
+
+	// The counters are advanced by the GC cycles and pauses since the last
+	// collection.
+	var (
+		mu        sync.Mutex
+		lastGC    uint32
+		lastPause uint64
+	)
+	r.OnCollect(func() {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		n, _ := runtime.ThreadCreateProfile(nil)
+
+		goroutines.Set(float64(runtime.NumGoroutine()))
+		threads.Set(float64(n))
+		alloc.Set(float64(ms.Alloc))
+		sys.Set(float64(ms.Sys))
+		heapInuse.Set(float64(ms.HeapInuse))
+		heapObjects.Set(float64(ms.HeapObjects))
+		mu.Lock()
+		gcCycles.Add(float64(ms.NumGC - lastGC))
+		gcPause.Add(time.Duration(ms.PauseTotalNs - lastPause).Seconds())
+		lastGC, lastPause = ms.NumGC, ms.PauseTotalNs
+		mu.Unlock()
+		uptime.Set(time.Since(startTime).Seconds())
+	})
+}
+
+func init() {
+	RegisterRuntimeMetrics(Default)
+}
@@ -28,13 +28,17 @@ package goboot
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 )
 
 // Params provides a unified view of the request params.
@@ -58,47 +62,134 @@ type Params struct {
 	Files    map[string][]*multipart.FileHeader // Files uploaded in a multipart form
 	tmpFiles []*os.File                         // Temp files used during the request.
 	JSON     []byte                             // JSON data from request body
+
+	files  []multipart.File // Uploaded files opened by the binders.
+	form   *multipart.Form  // Parsed multipart form, holding its own temp files.
+	parent *Params          // Params tracking the files of this one, see root.
+
+	ContentType string // Media type of the request body, without parameters.
+	Body        []byte // Request body of a media type having a BodyDecoder.
+
+	Binders *BinderRegistry // Binders overriding the global ones, set by the router.
+	Log     Logger          // Logger of the bind warnings, Log when nil; set by App.ParseParams.
 }
 
 // ParseParams parses the `http.Request` params into `revel.Controller.Params`
-func ParseParams(params *Params, req *http.Request) {
+// with the upload limits of Config, see UploadLimits.ParseParams.
+func ParseParams(params *Params, req *http.Request) error {
+	return Config.UploadLimits().ParseParams(params, req)
+}
+
+// ParseParams parses the `http.Request` params into params. Bodies over
+// MaxBodySize and uploaded files over MaxFileSize or of a type not in Types
+// are refused with a *ParamsError, as are malformed bodies; the query params
+// are set in any case.
+func (l UploadLimits) ParseParams(params *Params, req *http.Request) error {
 	params.Query = req.URL.Query()
+	params.Values = params.calcValues()
+
+	// Parse the body depending on the media type.
+	var mediaType string
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(ct); err != nil {
+			return &ParamsError{http.StatusBadRequest, fmt.Errorf("error parsing request content type: %v", err)}
+		}
+	}
+	params.ContentType = mediaType
+	if err := l.limitBody(req); err != nil {
+		return err
+	}
 
-	// Parse the body depending on the content type.
-	switch req.Header.Get("Content-Type") {
+	switch mediaType {
 	case "application/x-www-form-urlencoded":
 		// Typical form.
 		if err := req.ParseForm(); err != nil {
-			Log.Warning("Error parsing request body:", err)
-		} else {
-			params.Form = req.Form
+			return bodyError(err)
 		}
+		params.Form = req.Form
 
 	case "multipart/form-data":
 		// Multipart form.
-		// TODO: Extract the multipart form param so app can set it.
-		if err := req.ParseMultipartForm(32 << 20 /* 32 MB */); err != nil {
-			Log.Warning("Error parsing request body:", err)
-		} else {
-			params.Form = req.MultipartForm.Value
-			params.Files = req.MultipartForm.File
+		if err := req.ParseMultipartForm(l.MaxMemory); err != nil {
+			return bodyError(err)
 		}
-	case "application/json":
-		fallthrough
-	case "text/json":
-		if req.Body != nil {
-			if content, err := ioutil.ReadAll(req.Body); err == nil {
-				// We wont bind it until we determine what we are binding too
-				params.JSON = content
-			} else {
-				Log.Error("Failed to ready request body bytes", err)
+		for field, fileHeaders := range req.MultipartForm.File {
+			for _, fh := range fileHeaders {
+				if err := l.checkFile(field, fh.Filename, fh.Size, fh.Header.Get("Content-Type")); err != nil {
+					req.MultipartForm.RemoveAll()
+					return err
+				}
 			}
-		} else {
-			Log.Info("Json post received with empty body")
+		}
+		params.form = req.MultipartForm
+		params.Form = req.MultipartForm.Value
+		params.Files = req.MultipartForm.File
+
+	default:
+		if _, ok := bodyDecoder(mediaType); !ok || mediaType == "" {
+			break
+		}
+		if req.Body == nil {
+			params.logger().Info("Body post received with empty body:", mediaType)
+			break
+		}
+		content, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return bodyError(err)
+		}
+		// We wont bind it until we determine what we are binding too
+		params.Body = content
+		if isJSON(mediaType) {
+			params.JSON = content
 		}
 	}
 
 	params.Values = params.calcValues()
+	return nil
+}
+
+// Close releases the files of the request once it is handled: it closes the
+// uploaded files opened by the binders and removes the temp files holding the
+// uploads, so files bound from the params must not be used afterwards. It
+// returns the first error.
+func (p *Params) Close() error {
+	var first error
+	keep := func(err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	for _, f := range p.files {
+		keep(f.Close())
+	}
+	for _, f := range p.tmpFiles {
+		f.Close()
+		keep(os.Remove(f.Name()))
+	}
+	if p.form != nil {
+		keep(p.form.RemoveAll())
+	}
+	p.files, p.tmpFiles, p.form = nil, nil, nil
+	return first
+}
+
+// root returns the Params tracking the opened and temp files.
+func (p *Params) root() *Params {
+	for p.parent != nil {
+		p = p.parent
+	}
+	return p
+}
+
+// logger returns the Log of the root params, or the package Log.
+func (p *Params) logger() Logger {
+	if p != nil {
+		if l := p.root().Log; l != nil {
+			return l
+		}
+	}
+	return defaultLog()
 }
 
 // Bind looks for the named parameter, converts it to the requested type, and
@@ -124,20 +215,145 @@ func (p *Params) Bind(dest interface{}, name string) {
 	p.JSON = jsonData
 }
 
-// Bind binds the JSON data to the dest.
+// BindE is like Bind but returns a *BindError, leaving dest unchanged, when
+// the value can not be parsed.
+func (p *Params) BindE(dest interface{}, name string) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || !value.Elem().CanSet() {
+		return errors.New("BindE not a settable pointer: " + name)
+	}
+	value = value.Elem()
+
+	// Named binds ignore the json data, see Bind.
+	jsonData := p.JSON
+	p.JSON = nil
+	defer func() { p.JSON = jsonData }()
+	v, err := BindE(p, name, value.Type())
+	if err != nil {
+		return err
+	}
+	value.Set(v)
+	return nil
+}
+
+// Bind binds the JSON data to the dest. It does not check the validate tags,
+// use BindStruct or Validate.
 func (p *Params) BindJSON(dest interface{}) error {
 	value := reflect.ValueOf(dest)
 	if value.Kind() != reflect.Ptr {
-		Log.Warning("BindJSON not a pointer")
+		p.logger().Warning("BindJSON not a pointer")
 		return errors.New("BindJSON not a pointer")
 	}
 	if err := json.Unmarshal(p.JSON, dest); err != nil {
-		Log.Warning("W: bindMap: Unable to unmarshal request:", err)
+		p.logger().Warning("W: bindMap: Unable to unmarshal request:", err)
 		return err
 	}
 	return nil
 }
 
+// BindXML binds the XML body to the dest. Like BindJSON it does not check the
+// validate tags.
+func (p *Params) BindXML(dest interface{}) error {
+	if reflect.ValueOf(dest).Kind() != reflect.Ptr {
+		return errors.New("BindXML not a pointer")
+	}
+	return xml.Unmarshal(p.Body, dest)
+}
+
+// BindBody decodes the body to the dest with the BodyDecoder of its media
+// type. Like BindJSON it does not check the validate tags.
+func (p *Params) BindBody(dest interface{}) error {
+	if reflect.ValueOf(dest).Kind() != reflect.Ptr {
+		return errors.New("BindBody not a pointer")
+	}
+	if p.JSON == nil && p.Body == nil {
+		return fmt.Errorf("BindBody: no body to decode for %q", p.ContentType)
+	}
+	return p.decodeBody(dest)
+}
+
+// decodeBody decodes the JSON data, or the body with the BodyDecoder of its
+// media type, to dest. It does nothing without a body.
+func (p *Params) decodeBody(dest interface{}) error {
+	if p.JSON != nil {
+		return json.Unmarshal(p.JSON, dest)
+	}
+	if p.Body == nil {
+		return nil
+	}
+	d, ok := bodyDecoder(p.ContentType)
+	if !ok {
+		return fmt.Errorf("no decoder for %q", p.ContentType)
+	}
+	return d(p.Body, dest)
+}
+
+// BindStruct binds the request to the struct dest points to, and checks the
+// validate tags of its fields, see Validate.
+//
+// A JSON body, or a body having a BodyDecoder, is decoded into dest first.
+// Then each exported field having a param is bound like Bind, overriding the
+// body: route, query and form params win over the body. Fields are named by
+// their form tag, or their json tag, or the field name; nested struct fields
+// by dotted names, e.g. address.city, and the fields of embedded structs are
+// promoted. Fields without a param keep their value.
+//
+// The returned ValidationErrors also list the params that could not be
+// converted to their field type, with the rule "type".
+func (p *Params) BindStruct(dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return errors.New("BindStruct not a pointer to a struct")
+	}
+	if err := p.decodeBody(dest); err != nil {
+		return err
+	}
+
+	var errs ValidationErrors
+	// The body is not decoded again into the nested structs.
+	fields := &Params{Values: p.Values, Files: p.Files, parent: p}
+	for _, err := range bindFields(fields, "", value.Elem()) {
+		errs = append(errs, typeError(err))
+	}
+
+	switch err := Validate(dest).(type) {
+	case nil:
+	case ValidationErrors:
+		errs = append(errs, err...)
+	default:
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// has reports whether a param or file binds name, directly or as a struct,
+// slice or map prefix.
+func (p *Params) has(name string) bool {
+	for key := range p.Values {
+		if key == name || strings.HasPrefix(key, name+".") || strings.HasPrefix(key, name+"[") {
+			return true
+		}
+	}
+	for key := range p.Files {
+		if key == name || strings.HasPrefix(key, name+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeError reports a param that could not be bound as a FieldError with
+// the rule "type".
+func typeError(err error) FieldError {
+	if be, ok := err.(*BindError); ok {
+		return FieldError{Field: be.Name, Rule: "type", Param: be.Type.String(), Value: be.Value}
+	}
+	return FieldError{Rule: "type", Value: err.Error()}
+}
+
 // calcValues returns a unified view of the component param maps.
 func (p *Params) calcValues() url.Values {
 	numParams := len(p.Query) + len(p.Fixed) + len(p.Route) + len(p.Form)
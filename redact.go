@@ -0,0 +1,320 @@
+package goboot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	logging "github.com/op/go-logging"
+)
+
+// DefaultRedactMask replaces every redacted value.
+const DefaultRedactMask = "******"
+
+// maxRedactDepth limits how deep nested maps and structs are walked.
+const maxRedactDepth = 5
+
+var (
+	// DefaultRedactKeys are the key name patterns whose values are masked,
+	// matched case-insensitively anywhere in a map key, struct field name,
+	// header name or key=value pair of the message.
+	DefaultRedactKeys = []string{"password", "passwd", "secret", "token", "authorization", "api[_-]?key", "cookie"}
+
+	// DefaultRedactPatterns are masked wherever they appear in a message.
+	// Matches of the pattern named "card" are only masked when they pass
+	// the Luhn check, to keep long numeric ids and timestamps readable.
+	DefaultRedactPatterns = map[string]string{
+		"card":  `\b(?:\d[ -]?){12,18}\d\b`,
+		"phone": `(?:\+\d{1,3}[ -]?\d{4,14}\b|\b1[3-9]\d{9}\b|\(\d{3}\) ?\d{3}-\d{4}\b)`,
+	}
+)
+
+// Redacted wraps a value that must never be written to the log, e.g.
+//
+//	Log.Debug("login", user, goboot.Redacted{password})
+type Redacted struct {
+	Value interface{}
+}
+
+func (r Redacted) String() string {
+	return DefaultRedactMask
+}
+
+// GoString keeps %#v from printing the wrapped value.
+func (r Redacted) GoString() string {
+	return DefaultRedactMask
+}
+
+// Redacted implements logging.Redactor.
+func (r Redacted) Redacted() interface{} {
+	return DefaultRedactMask
+}
+
+func (r Redacted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(DefaultRedactMask)
+}
+
+// RedactFilter masks credentials and personal data in log arguments and
+// messages.
+type RedactFilter struct {
+	Mask     string
+	keys     *regexp.Regexp
+	pairs    *regexp.Regexp
+	patterns map[string]*regexp.Regexp
+}
+
+// NewRedactFilter compiles a filter from key name patterns and named message
+// patterns, see DefaultRedactKeys and DefaultRedactPatterns. Blank key
+// patterns are ignored, they would match every key.
+func NewRedactFilter(keys []string, patterns map[string]string) (*RedactFilter, error) {
+	f := &RedactFilter{
+		Mask:     DefaultRedactMask,
+		patterns: make(map[string]*regexp.Regexp),
+	}
+
+	var nonBlank []string
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			nonBlank = append(nonBlank, k)
+		}
+	}
+	keys = nonBlank
+
+	if len(keys) > 0 {
+		alt := "(?:" + strings.Join(keys, "|") + ")"
+		var err error
+		if f.keys, err = regexp.Compile("(?i)" + alt); err != nil {
+			return nil, err
+		}
+		f.pairs = regexp.MustCompile(`(?i)(` + alt + `[\w.-]*["']?\s*[:=]\s*["']?(?:(?:bearer|basic|token)\s+)?)[^\s"'&,;)}\]]+`)
+	}
+
+	for name, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %s: %v", name, err)
+		}
+		f.patterns[name] = re
+	}
+	return f, nil
+}
+
+// NewRedactFilterWithConfig builds the filter from the log.redact.* keys, it
+// returns nil when log.redact is false. log.redact.keys is a comma separated
+// list replacing DefaultRedactKeys, each log.redact.pattern.<name> key adds or
+// replaces a pattern, an empty value or "off" disables it.
+func NewRedactFilterWithConfig(c *ConfigContext) *RedactFilter {
+	if !c.MustBool(IniLogRedact, true) {
+		return nil
+	}
+
+	keys := DefaultRedactKeys
+	if c.MustString(IniLogRedactKeys) != "" {
+		keys = c.MustStringArray(IniLogRedactKeys, ",")
+	}
+
+	patterns := make(map[string]string)
+	for name, p := range DefaultRedactPatterns {
+		patterns[name] = p
+	}
+	for name, p := range c.MustStringMap(IniLogRedactPattern + ".") {
+		if p == "" || p == "off" {
+			delete(patterns, name)
+			continue
+		}
+		patterns[name] = p
+	}
+
+	f, err := NewRedactFilter(keys, patterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboot: %v, using default redaction\n", err)
+		f, _ = NewRedactFilter(DefaultRedactKeys, DefaultRedactPatterns)
+	}
+	f.Mask = c.MustString(IniLogRedactMask, DefaultRedactMask)
+	return f
+}
+
+// MatchKey reports whether values stored under name must be masked.
+func (f *RedactFilter) MatchKey(name string) bool {
+	return f.keys != nil && f.keys.MatchString(name)
+}
+
+// RedactString masks key=value pairs with sensitive keys and every match of
+// the message patterns in s.
+func (f *RedactFilter) RedactString(s string) string {
+	mask := strings.Replace(f.Mask, "$", "$$", -1)
+	if f.pairs != nil {
+		s = f.pairs.ReplaceAllString(s, "${1}"+mask)
+	}
+
+	names := make([]string, 0, len(f.patterns))
+	for name := range f.patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		re := f.patterns[name]
+		if name != "card" {
+			s = re.ReplaceAllString(s, mask)
+			continue
+		}
+		s = re.ReplaceAllStringFunc(s, func(m string) string {
+			if luhn(m) {
+				return f.Mask
+			}
+			return m
+		})
+	}
+	return s
+}
+
+// RedactRecord returns the redacted arguments of rec and its message rendered
+// with them, then passed through RedactString. rec is not modified, it may
+// be shared with other backends.
+func (f *RedactFilter) RedactRecord(rec *logging.Record) (args []interface{}, msg string) {
+	args = make([]interface{}, len(rec.Args))
+	for i, arg := range rec.Args {
+		args[i] = f.Redact(arg)
+	}
+	tmp := *rec
+	tmp.Args = args
+	return args, f.RedactString(tmp.Message())
+}
+
+// Redact returns a copy of v with the values of sensitive map keys and
+// struct fields masked. Values of other types are returned unchanged.
+func (f *RedactFilter) Redact(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	rv := f.redactValue(reflect.ValueOf(v), 0)
+	if !rv.IsValid() || !rv.CanInterface() {
+		return v
+	}
+	return rv.Interface()
+}
+
+func (f *RedactFilter) redactValue(v reflect.Value, depth int) reflect.Value {
+	if depth > maxRedactDepth {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		out := reflect.MakeMap(v.Type())
+		for _, k := range v.MapKeys() {
+			e := v.MapIndex(k)
+			if f.MatchKey(k.String()) {
+				e = f.maskValue(e.Type())
+			} else {
+				e = f.redactValue(e, depth+1)
+			}
+			out.SetMapIndex(k, e)
+		}
+		return out
+	case reflect.Ptr:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return v
+		}
+		cp := reflect.New(v.Elem().Type())
+		cp.Elem().Set(f.redactValue(v.Elem(), depth+1))
+		return cp
+	case reflect.Struct:
+		typ := v.Type()
+		cp := reflect.New(typ).Elem()
+		cp.Set(v)
+		for i := 0; i < typ.NumField(); i++ {
+			field := cp.Field(i)
+			// PkgPath is specified to be empty exactly for exported fields.
+			if typ.Field(i).PkgPath != "" || !field.CanSet() {
+				continue
+			}
+			if f.MatchKey(typ.Field(i).Name) {
+				field.Set(f.maskValue(field.Type()))
+			} else if r := f.redactValue(field, depth+1); r.Type().AssignableTo(field.Type()) {
+				field.Set(r)
+			}
+		}
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		return f.redactValue(v.Elem(), depth+1)
+	}
+	return v
+}
+
+// maskValue returns the mask converted to typ, or the zero value when typ can
+// not hold a string.
+func (f *RedactFilter) maskValue(typ reflect.Type) reflect.Value {
+	mask := reflect.ValueOf(f.Mask)
+	switch {
+	case typ.Kind() == reflect.String:
+		return mask.Convert(typ)
+	case typ.Kind() == reflect.Interface && mask.Type().AssignableTo(typ):
+		v := reflect.New(typ).Elem()
+		v.Set(mask)
+		return v
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.String:
+		v := reflect.MakeSlice(typ, 1, 1)
+		v.Index(0).Set(mask.Convert(typ.Elem()))
+		return v
+	}
+	return reflect.Zero(typ)
+}
+
+// luhn reports whether the digits in s pass the Luhn checksum.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// redactBackend applies a RedactFilter to every record before handing it to
+// the wrapped backend.
+type redactBackend struct {
+	backend logging.Backend
+	filter  *RedactFilter
+}
+
+// NewRedactBackend wraps b so that records are redacted by f before being
+// formatted and written.
+func NewRedactBackend(b logging.Backend, f *RedactFilter) logging.Backend {
+	if f == nil {
+		return b
+	}
+	return &redactBackend{backend: b, filter: f}
+}
+
+func (rb *redactBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	_, msg := rb.filter.RedactRecord(rec)
+	return rb.backend.Log(level, calldepth+1, &logging.Record{
+		ID:     rec.ID,
+		Time:   rec.Time,
+		Module: rec.Module,
+		Level:  rec.Level,
+		Args:   []interface{}{msg},
+	})
+}
@@ -0,0 +1,92 @@
+package goboot
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	logging "github.com/op/go-logging"
+)
+
+func TestRedactString(t *testing.T) {
+	f, err := NewRedactFilter(DefaultRedactKeys, DefaultRedactPatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"user=bob password=hunter2":             "user=bob password=******",
+		`{"access_token":"abc.def"}`:            `{"access_token":"******"}`,
+		"Authorization: Bearer abc":             "Authorization: Bearer ******",
+		"card 4111 1111 1111 1111 charged":      "card ****** charged",
+		"order 1234567890123 created":           "order 1234567890123 created",
+		"call 13800138000 or +86 13800138000":   "call ****** or ******",
+		"nothing to see at 2016-09-22 10:45:46": "nothing to see at 2016-09-22 10:45:46",
+	}
+	for in, want := range cases {
+		if got := f.RedactString(in); got != want {
+			t.Errorf("RedactString(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRedactBlankKeys(t *testing.T) {
+	c := NewConfigWithoutFile("test")
+	c.RunModeSection.NewKey(IniLogRedactKeys, "password, token,, ")
+	f := NewRedactFilterWithConfig(c)
+
+	if !f.MatchKey("password") || !f.MatchKey("Token") || f.MatchKey("user") {
+		t.Error("keys", f.keys)
+	}
+	if got := f.RedactString("user=bob token=abc"); got != "user=bob token=******" {
+		t.Errorf("RedactString = %q", got)
+	}
+	if got := f.Redact(map[string]string{"user": "bob"}); got.(map[string]string)["user"] != "bob" {
+		t.Errorf("Redact = %v", got)
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	f, _ := NewRedactFilter(DefaultRedactKeys, nil)
+
+	v := url.Values{"user": {"bob"}, "Password": {"hunter2"}}
+	r := f.Redact(v).(url.Values)
+	if r.Get("Password") != DefaultRedactMask || r.Get("user") != "bob" {
+		t.Error("url.Values", r)
+	}
+	if v.Get("Password") != "hunter2" {
+		t.Error("original value modified")
+	}
+
+	type login struct {
+		User     string
+		Password string
+		APIKey   []string
+	}
+	l := f.Redact(&login{"bob", "hunter2", []string{"k"}}).(*login)
+	if l.User != "bob" || l.Password != DefaultRedactMask || l.APIKey[0] != DefaultRedactMask {
+		t.Error("struct", l)
+	}
+
+	if s := fmt.Sprint(Redacted{"hunter2"}); s != DefaultRedactMask {
+		t.Error("Redacted", s)
+	}
+}
+
+func TestRedactBackend(t *testing.T) {
+	var buf bytes.Buffer
+	f, _ := NewRedactFilter(DefaultRedactKeys, DefaultRedactPatterns)
+	b := logging.NewBackendFormatter(logging.NewLogBackend(&buf, "", 0), logging.MustStringFormatter("%{message}"))
+	l := logging.MustGetLogger("redact")
+	l.SetBackend(logging.AddModuleLevel(NewRedactBackend(b, f)))
+
+	l.Info("params", url.Values{"token": {"abc"}}, Redacted{"hunter2"})
+	l.Infof("secret=%s", "xyz")
+
+	out := buf.String()
+	if strings.Contains(out, "abc") || strings.Contains(out, "hunter2") || strings.Contains(out, "xyz") {
+		t.Error("secret leaked:", out)
+	}
+}
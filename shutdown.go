@@ -0,0 +1,190 @@
+package goboot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds the stop hooks when app.shutdown.timeout is not set.
+const DefaultShutdownTimeout = 30 * time.Second
+
+type StopHook struct {
+	order int
+	f     func(ctx context.Context) error
+}
+
+type StopHooks []StopHook
+
+// exit is replaced in tests.
+var exit = os.Exit
+
+func (slice StopHooks) Len() int {
+	return len(slice)
+}
+
+func (slice StopHooks) Less(i, j int) bool {
+	return slice[i].order < slice[j].order
+}
+
+func (slice StopHooks) Swap(i, j int) {
+	slice[i], slice[j] = slice[j], slice[i]
+}
+
+// OnAppStop registers f to run at shutdown. Stop hooks run in the reverse
+// order of startup hooks: highest order first, and among equal orders the
+// last registered first.
+func OnAppStop(f func() error, order ...int) {
+	defaultApp.OnAppStop(f, order...)
+}
+
+// OnAppStopContext is like OnAppStop, ctx is cancelled when the
+// app.shutdown.timeout deadline expires.
+func OnAppStopContext(f func(ctx context.Context) error, order ...int) {
+	defaultApp.OnAppStopContext(f, order...)
+}
+
+func (a *App) OnAppStop(f func() error, order ...int) {
+	a.OnAppStopContext(func(context.Context) error { return f() }, order...)
+}
+
+func (a *App) OnAppStopContext(f func(ctx context.Context) error, order ...int) {
+	o := 1
+	if len(order) > 0 {
+		o = order[0]
+	}
+	a.mu.Lock()
+	a.stopHooks = append(a.stopHooks, StopHook{order: o, f: f})
+	a.mu.Unlock()
+}
+
+// runStopHooks runs every stop hook, the remaining hooks are abandoned once
+// ctx is done.
+func (a *App) runStopHooks(ctx context.Context) error {
+	a.mu.Lock()
+	hooks := make(StopHooks, len(a.stopHooks))
+	copy(hooks, a.stopHooks)
+	a.mu.Unlock()
+	sort.Stable(hooks)
+
+	var errs MultiError
+	for i := len(hooks) - 1; i >= 0; i-- {
+		done := make(chan error, 1)
+		go func(f func(context.Context) error) {
+			done <- f(ctx)
+		}(hooks[i].f)
+
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("shutdown: %d stop hooks not finished: %w", i+1, ctx.Err()))
+			return errs
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// Shutdown marks the app as not ready, runs the stop hooks once within
+// app.shutdown.timeout and returns their errors, later calls return the result
+// of the first one.
+func Shutdown() error {
+	return syncDefault().Shutdown()
+}
+
+// Stop makes Run shut down as if it received SIGTERM.
+func Stop() {
+	defaultApp.Stop()
+}
+
+// Run starts the application, blocks until SIGINT, SIGTERM or Stop, runs the
+// stop hooks and exits the process. The exit status is 1 when a startup hook
+// or a stop hook failed or did not finish in time.
+func Run() {
+	if err := syncDefault().Run(); err != nil {
+		exit(1)
+		return
+	}
+	exit(0)
+}
+
+// Shutdown stops the scheduler and then works like the package level
+// Shutdown.
+func (a *App) Shutdown() error {
+	a.shutdownOnce.Do(func() {
+		a.setReady(false)
+		if a.Scheduler != nil {
+			a.Scheduler.Stop()
+		}
+
+		timeout := DefaultShutdownTimeout
+		if a.Config != nil {
+			timeout = a.Config.MustDuration(IniAppShutdownTimeout, DefaultShutdownTimeout)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+		a.shutdownErr = a.runStopHooks(ctx)
+		a.Log.Infof("shutdown finished in %s", time.Since(start))
+	})
+	return a.shutdownErr
+}
+
+// Stop makes Run shut down as if it received SIGTERM.
+func (a *App) Stop() {
+	a.stopOnce.Do(func() { close(a.stopCh) })
+}
+
+// Run is like the package level Run but returns the startup or shutdown error
+// instead of exiting. A signal or Stop during Startup cancels the startup
+// hooks still running, skips the others and shuts down.
+func (a *App) Run() error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan error, 1)
+	go func() {
+		started <- a.startup(ctx)
+	}()
+
+	select {
+	case err := <-started:
+		if err != nil {
+			a.Log.Error("startup:", err)
+			if err := a.Shutdown(); err != nil {
+				a.Log.Error("shutdown:", err)
+			}
+			return err
+		}
+		select {
+		case sig := <-sigs:
+			a.Log.Infof("received %s, shutting down", sig)
+		case <-a.stopCh:
+			a.Log.Info("stop requested, shutting down")
+		}
+	case sig := <-sigs:
+		a.Log.Infof("received %s during startup, shutting down", sig)
+		cancel()
+		<-started
+	case <-a.stopCh:
+		a.Log.Info("stop requested during startup, shutting down")
+		cancel()
+		<-started
+	}
+
+	if err := a.Shutdown(); err != nil {
+		a.Log.Error("shutdown:", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package goboot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunStopHooksOrder(t *testing.T) {
+	a := newTestApp(t)
+
+	var got []string
+	a.OnAppStop(func() error { got = append(got, "a1"); return nil })
+	a.OnAppStop(func() error { got = append(got, "b0"); return errors.New("b0 failed") }, 0)
+	a.OnAppStop(func() error { got = append(got, "c1"); return nil })
+	a.OnAppStop(func() error { got = append(got, "d9"); return nil }, 9)
+
+	err := a.runStopHooks(context.Background())
+	if want := []string{"d9", "c1", "a1", "b0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order %v, want %v", got, want)
+	}
+	if err == nil || err.Error() != "b0 failed" {
+		t.Error("error", err)
+	}
+}
+
+func TestRunStopHooksTimeout(t *testing.T) {
+	a := newTestApp(t)
+
+	a.OnAppStopContext(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := a.runStopHooks(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected deadline error, got", err)
+	}
+}
+
+func TestRunSignalDuringStartup(t *testing.T) {
+	a := newTestApp(t)
+
+	started := make(chan struct{})
+	a.OnAppStartContext(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	a.OnAppStart(func() error {
+		t.Error("later startup hook ran")
+		return nil
+	}, 2)
+	stopped := false
+	a.OnAppStop(func() error { stopped = true; return nil })
+
+	go func() {
+		<-started
+		syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	}()
+	done := make(chan error, 1)
+	go func() { done <- a.Run() }()
+	select {
+	case err := <-done:
+		if err != nil || !stopped {
+			t.Errorf("run %v, stop hook ran %v", err, stopped)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("startup not cancelled")
+	}
+}
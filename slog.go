@@ -0,0 +1,272 @@
+package goboot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	logging "github.com/op/go-logging"
+)
+
+// slog levels for the go-logging levels slog has no name for.
+const (
+	LevelNotice   = slog.Level(2)
+	LevelCritical = slog.Level(12)
+)
+
+// slogCalldepth skips slog.(*Logger).Info, slog.(*Logger).log and
+// slogHandler.Handle so %{shortfile} points at the slog caller.
+const slogCalldepth = 3
+
+func loggingLevel(l slog.Level) logging.Level {
+	switch {
+	case l >= LevelCritical:
+		return logging.CRITICAL
+	case l >= slog.LevelError:
+		return logging.ERROR
+	case l >= slog.LevelWarn:
+		return logging.WARNING
+	case l >= LevelNotice:
+		return logging.NOTICE
+	case l >= slog.LevelInfo:
+		return logging.INFO
+	default:
+		return logging.DEBUG
+	}
+}
+
+func slogLevel(l logging.Level) slog.Level {
+	switch l {
+	case logging.CRITICAL:
+		return LevelCritical
+	case logging.ERROR:
+		return slog.LevelError
+	case logging.WARNING:
+		return slog.LevelWarn
+	case logging.NOTICE:
+		return LevelNotice
+	case logging.INFO:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
+
+// slogHandler is a slog.Handler writing to the goboot logging backends.
+type slogHandler struct {
+	logger *logging.Logger
+	attrs  Fields
+	group  string
+}
+
+// NewSlogHandler returns a slog.Handler writing records to the goboot logging
+// backends under module, attributes are passed on as Fields, e.g.
+//
+//	slog.SetDefault(slog.New(goboot.NewSlogHandler("app")))
+func NewSlogHandler(module string) slog.Handler {
+	l := logging.MustGetLogger(module)
+	l.ExtraCalldepth = slogCalldepth
+	return &slogHandler{logger: l}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.IsEnabledFor(loggingLevel(level))
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(Fields, len(h.attrs)+r.NumAttrs())
+	for k, v := range h.attrs {
+		fields[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, h.group, a)
+		return true
+	})
+
+	args := []interface{}{r.Message}
+	if len(fields) > 0 {
+		args = append(args, fields)
+	}
+
+	switch loggingLevel(r.Level) {
+	case logging.CRITICAL:
+		h.logger.Critical(args...)
+	case logging.ERROR:
+		h.logger.Error(args...)
+	case logging.WARNING:
+		h.logger.Warning(args...)
+	case logging.NOTICE:
+		h.logger.Notice(args...)
+	case logging.INFO:
+		h.logger.Info(args...)
+	default:
+		h.logger.Debug(args...)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = make(Fields, len(h.attrs)+len(attrs))
+	for k, v := range h.attrs {
+		h2.attrs[k] = v
+	}
+	for _, a := range attrs {
+		addAttr(h2.attrs, h.group, a)
+	}
+	return &h2
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+// addAttr flattens a into fields, group members are prefixed with the group
+// names joined by dots.
+func addAttr(fields Fields, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(fields, p, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	fields[prefix+a.Key] = v.Any()
+}
+
+// slogLogger adapts a *slog.Logger to Logger.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to l, install it with
+//
+//	goboot.Log = goboot.NewSlogLogger(slog.Default())
+//
+// Fields arguments are passed on as slog attributes.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{logger: l}
+}
+
+func (s *slogLogger) log(level logging.Level, format *string, args ...interface{}) {
+	ctx := context.Background()
+	lvl := slogLevel(level)
+	if !s.logger.Enabled(ctx, lvl) {
+		return
+	}
+
+	var (
+		msg    string
+		fields Fields
+	)
+	if format != nil {
+		msg = fmt.Sprintf(*format, args...)
+	} else {
+		rest := make([]interface{}, 0, len(args))
+		for _, arg := range args {
+			if _, ok := arg.(Fields); !ok {
+				rest = append(rest, arg)
+			}
+		}
+		// use Sprintln to get spaces between all arguments, as go-logging does
+		msg = strings.TrimSuffix(fmt.Sprintln(rest...), "\n")
+		fields = FieldsOf(args)
+	}
+
+	// skip runtime.Callers, log and the level method
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), lvl, msg, pcs[0])
+	for k, v := range fields {
+		r.AddAttrs(slog.Any(k, v))
+	}
+	s.logger.Handler().Handle(ctx, r)
+}
+
+// Fatal is Critical followed by os.Exit(1).
+func (s *slogLogger) Fatal(args ...interface{}) {
+	s.log(logging.CRITICAL, nil, args...)
+	os.Exit(1)
+}
+
+// Fatalf is Criticalf followed by os.Exit(1).
+func (s *slogLogger) Fatalf(format string, args ...interface{}) {
+	s.log(logging.CRITICAL, &format, args...)
+	os.Exit(1)
+}
+
+// Panic is Critical followed by a panic.
+func (s *slogLogger) Panic(args ...interface{}) {
+	s.log(logging.CRITICAL, nil, args...)
+	panic(fmt.Sprint(args...))
+}
+
+// Panicf is Criticalf followed by a panic.
+func (s *slogLogger) Panicf(format string, args ...interface{}) {
+	s.log(logging.CRITICAL, &format, args...)
+	panic(fmt.Sprintf(format, args...))
+}
+
+func (s *slogLogger) Critical(args ...interface{}) {
+	s.log(logging.CRITICAL, nil, args...)
+}
+
+func (s *slogLogger) Criticalf(format string, args ...interface{}) {
+	s.log(logging.CRITICAL, &format, args...)
+}
+
+func (s *slogLogger) Error(args ...interface{}) {
+	s.log(logging.ERROR, nil, args...)
+}
+
+func (s *slogLogger) Errorf(format string, args ...interface{}) {
+	s.log(logging.ERROR, &format, args...)
+}
+
+func (s *slogLogger) Warning(args ...interface{}) {
+	s.log(logging.WARNING, nil, args...)
+}
+
+func (s *slogLogger) Warningf(format string, args ...interface{}) {
+	s.log(logging.WARNING, &format, args...)
+}
+
+func (s *slogLogger) Notice(args ...interface{}) {
+	s.log(logging.NOTICE, nil, args...)
+}
+
+func (s *slogLogger) Noticef(format string, args ...interface{}) {
+	s.log(logging.NOTICE, &format, args...)
+}
+
+func (s *slogLogger) Info(args ...interface{}) {
+	s.log(logging.INFO, nil, args...)
+}
+
+func (s *slogLogger) Infof(format string, args ...interface{}) {
+	s.log(logging.INFO, &format, args...)
+}
+
+func (s *slogLogger) Debug(args ...interface{}) {
+	s.log(logging.DEBUG, nil, args...)
+}
+
+func (s *slogLogger) Debugf(format string, args ...interface{}) {
+	s.log(logging.DEBUG, &format, args...)
+}
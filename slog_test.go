@@ -0,0 +1,54 @@
+package goboot
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	logging "github.com/op/go-logging"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	b := logging.AddModuleLevel(logging.NewBackendFormatter(logging.NewLogBackend(&buf, "", 0),
+		logging.MustStringFormatter("%{level} %{shortfile} %{message}")))
+	b.SetLevel(logging.INFO, "slog")
+	prev := SetLogBackend(b)
+	defer SetLogBackend(prev)
+
+	l := slog.New(NewSlogHandler("slog")).With("app", "demo")
+	l.Debug("hidden")
+	l.WithGroup("req").Warn("slow request", "ms", 1200)
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Error("debug record not filtered:", out)
+	}
+	if !strings.Contains(out, "WARNING slog_test.go") || !strings.Contains(out, "slow request app=demo req.ms=1200") {
+		t.Error(out)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{AddSource: true})))
+
+	l.Notice("user", "login", Fields{"id": 42})
+	l.Errorf("failed %d times", 3)
+
+	out := buf.String()
+	if !strings.Contains(out, `level=INFO+2`) || !strings.Contains(out, `msg="user login" id=42`) {
+		t.Error(out)
+	}
+	if !strings.Contains(out, `msg="failed 3 times"`) || !strings.Contains(out, "slog_test.go") {
+		t.Error(out)
+	}
+
+	defer func() {
+		if r := recover(); r != "bad state 7" || !strings.Contains(buf.String(), `level=ERROR+4 source=`) {
+			t.Errorf("recovered %v\n%s", r, buf.String())
+		}
+	}()
+	l.Panicf("bad state %d", 7)
+}
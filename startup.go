@@ -1,37 +1,49 @@
 package goboot
 
-import "sort"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
 
+// StartupHook is a function run by Startup.
+//
+// Hooks run after every hook named in After. A hook without After runs after
+// all other hooks without After that have a lower Order, hooks of the same
+// Order run in registration order; an Order of 0 is DefaultStartupOrder.
+// When a hook fails, the hooks depending on it are skipped and the others
+// still run.
+//
+// Consecutive Parallel hooks of the same Order form a phase and run
+// concurrently. The phase fails as a unit: the first error cancels the context
+// of the other members and every member counts as failed for its dependents.
 type StartupHook struct {
-	order int
-	f     func() error
-}
+	Name     string        // unique name, defaults to the name of Func
+	Order    int           // tie breaker between hooks whose dependencies are met
+	After    []string      // names of the hooks that must succeed first
+	Parallel bool          // may run concurrently with the other Parallel hooks of its Order
+	Timeout  time.Duration // defaults to app.startup.timeout, 0 means no timeout
 
-type StartupHooks []StartupHook
+	Func        func() error
+	FuncContext func(ctx context.Context) error // used instead of Func when set
+}
 
-var startupHooks StartupHooks
+// DefaultStartupOrder is the Order of the hooks registered without one.
+const DefaultStartupOrder = 1
 
-func runStartupHooks() {
-	var err error
-	runFunc := func(f func() error) {
-		if err != nil {
-			panic(err)
-		}
-		err = f()
-	}
-
-	sort.Sort(startupHooks)
-	for _, hook := range startupHooks {
-		runFunc(hook.f)
-	}
-}
+type StartupHooks []StartupHook
 
 func (slice StartupHooks) Len() int {
 	return len(slice)
 }
 
 func (slice StartupHooks) Less(i, j int) bool {
-	return slice[i].order < slice[j].order
+	return slice[i].Order < slice[j].Order
 }
 
 func (slice StartupHooks) Swap(i, j int) {
@@ -39,9 +51,301 @@ func (slice StartupHooks) Swap(i, j int) {
 }
 
 func OnAppStart(f func() error, order ...int) {
-	o := 1
+	defaultApp.OnAppStart(f, order...)
+}
+
+// OnAppStartContext is like OnAppStart, ctx is cancelled when the hook times
+// out.
+func OnAppStartContext(f func(ctx context.Context) error, order ...int) {
+	defaultApp.OnAppStartContext(f, order...)
+}
+
+// OnAppStartHook registers a named startup hook, e.g.
+//
+//	g.OnAppStartHook(g.StartupHook{Name: "consumer", After: []string{"redis", "db"}, Func: startConsumer})
+func OnAppStartHook(h StartupHook) {
+	defaultApp.OnAppStartHook(h)
+}
+
+func (a *App) OnAppStart(f func() error, order ...int) {
+	o := DefaultStartupOrder
+	if len(order) > 0 {
+		o = order[0]
+	}
+	a.addStartupHook(StartupHook{Order: o, Func: f})
+}
+
+func (a *App) OnAppStartContext(f func(ctx context.Context) error, order ...int) {
+	o := DefaultStartupOrder
 	if len(order) > 0 {
 		o = order[0]
 	}
-	startupHooks = append(startupHooks, StartupHook{order: o, f: f})
+	a.addStartupHook(StartupHook{Order: o, FuncContext: f})
+}
+
+// OnAppStartHook registers h, an Order of 0 is DefaultStartupOrder as for
+// OnAppStart without an order.
+func (a *App) OnAppStartHook(h StartupHook) {
+	if h.Order == 0 {
+		h.Order = DefaultStartupOrder
+	}
+	a.addStartupHook(h)
+}
+
+// addStartupHook registers h with its Order as is, OnAppStart(f, 0) runs
+// before the hooks of the default order.
+func (a *App) addStartupHook(h StartupHook) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if h.Name == "" && h.FuncContext != nil {
+		h.Name = a.uniqueHookName(funcName(h.FuncContext))
+	} else if h.Name == "" {
+		h.Name = a.uniqueHookName(funcName(h.Func))
+	}
+	a.startupHooks = append(a.startupHooks, h)
+}
+
+func funcName(f interface{}) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return "(unnamed)"
+}
+
+func (a *App) uniqueHookName(name string) string {
+	n := name
+	for i := 2; ; i++ {
+		taken := false
+		for _, h := range a.startupHooks {
+			if h.Name == n {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return n
+		}
+		n = fmt.Sprintf("%s#%d", name, i)
+	}
+}
+
+// startupOrder sorts hooks so that every hook follows its dependencies, ties
+// are broken by Order and then by registration order. It also returns the
+// dependencies of each hook.
+func startupOrder(hooks StartupHooks) (StartupHooks, map[string][]string, error) {
+	sorted := make(StartupHooks, len(hooks))
+	copy(sorted, hooks)
+	sort.Stable(sorted)
+
+	names := make(map[string]bool, len(sorted))
+	for _, h := range sorted {
+		if names[h.Name] {
+			return nil, nil, fmt.Errorf("startup hook %s registered twice", h.Name)
+		}
+		names[h.Name] = true
+	}
+
+	deps := make(map[string][]string, len(sorted))
+	for _, h := range sorted {
+		if len(h.After) == 0 {
+			for _, o := range sorted {
+				if len(o.After) == 0 && o.Order < h.Order {
+					deps[h.Name] = append(deps[h.Name], o.Name)
+				}
+			}
+			continue
+		}
+		for _, d := range h.After {
+			if !names[d] {
+				return nil, nil, fmt.Errorf("startup hook %s depends on unknown hook %s", h.Name, d)
+			}
+		}
+		deps[h.Name] = h.After
+	}
+
+	done := make(map[string]bool, len(sorted))
+	ordered := make(StartupHooks, 0, len(sorted))
+	for len(ordered) < len(sorted) {
+		next := -1
+		for i, h := range sorted {
+			if !done[h.Name] && allDone(deps[h.Name], done) {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			var pending []string
+			for _, h := range sorted {
+				if !done[h.Name] {
+					pending = append(pending, h.Name)
+				}
+			}
+			return nil, nil, fmt.Errorf("startup hooks have circular dependencies: %s", strings.Join(pending, ", "))
+		}
+		done[sorted[next].Name] = true
+		ordered = append(ordered, sorted[next])
+	}
+	return ordered, deps, nil
+}
+
+func allDone(names []string, done map[string]bool) bool {
+	for _, n := range names {
+		if !done[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// startupPhases groups consecutive Parallel hooks of the same Order that do
+// not depend on each other, every other hook is a phase of its own.
+func startupPhases(hooks StartupHooks) []StartupHooks {
+	var phases []StartupHooks
+	for _, h := range hooks {
+		if n := len(phases); n > 0 && h.Parallel {
+			last := phases[n-1]
+			if last[0].Parallel && last[0].Order == h.Order && !dependsOn(h, last) {
+				phases[n-1] = append(last, h)
+				continue
+			}
+		}
+		phases = append(phases, StartupHooks{h})
+	}
+	return phases
+}
+
+func dependsOn(h StartupHook, hooks StartupHooks) bool {
+	for _, d := range h.After {
+		for _, o := range hooks {
+			if o.Name == d {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callHook runs the hook function, turning a panic into an error.
+func callHook(ctx context.Context, h StartupHook) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if h.FuncContext != nil {
+		return h.FuncContext(ctx)
+	}
+	return h.Func()
+}
+
+// runStartupHook runs h within its timeout. A hook ignoring ctx is abandoned
+// when the timeout expires.
+func (a *App) runStartupHook(ctx context.Context, h StartupHook) error {
+	timeout := h.Timeout
+	if timeout == 0 && a.Config != nil {
+		timeout = a.Config.MustDuration(IniAppStartupTimeout)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- callHook(ctx, h)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		a.Log.Errorf("startup hook %s failed after %s: %v", h.Name, time.Since(start), err)
+		return fmt.Errorf("startup hook %s: %w", h.Name, err)
+	}
+	a.Log.Infof("startup hook %s finished in %s", h.Name, time.Since(start))
+	return nil
+}
+
+// runPhase runs the hooks concurrently, the first failure cancels the others.
+func (a *App) runPhase(ctx context.Context, hooks StartupHooks) MultiError {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]error, len(hooks))
+	var wg sync.WaitGroup
+	for i, h := range hooks {
+		wg.Add(1)
+		go func(i int, h StartupHook) {
+			defer wg.Done()
+			if results[i] = a.runStartupHook(ctx, h); results[i] != nil {
+				cancel()
+			}
+		}(i, h)
+	}
+	wg.Wait()
+
+	var errs MultiError
+	for _, err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// runStartupHooks runs the hooks phase by phase, the phases left once ctx is
+// done are skipped.
+func (a *App) runStartupHooks(ctx context.Context) error {
+	a.mu.Lock()
+	registered := make(StartupHooks, len(a.startupHooks))
+	copy(registered, a.startupHooks)
+	a.mu.Unlock()
+
+	hooks, deps, err := startupOrder(registered)
+	if err != nil {
+		return err
+	}
+
+	var errs MultiError
+	failed := make(map[string]bool)
+	for _, phase := range startupPhases(hooks) {
+		if ctx.Err() != nil {
+			return append(errs, fmt.Errorf("startup: %w", ctx.Err()))
+		}
+		var run StartupHooks
+		for _, hook := range phase {
+			if dep := firstFailed(deps[hook.Name], failed); dep != "" {
+				failed[hook.Name] = true
+				errs = append(errs, fmt.Errorf("startup hook %s: skipped, %s failed", hook.Name, dep))
+				continue
+			}
+			run = append(run, hook)
+		}
+		if len(run) == 0 {
+			continue
+		}
+
+		if phaseErrs := a.runPhase(ctx, run); len(phaseErrs) > 0 {
+			for _, hook := range run {
+				failed[hook.Name] = true
+			}
+			errs = append(errs, phaseErrs...)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+func firstFailed(names []string, failed map[string]bool) string {
+	for _, n := range names {
+		if failed[n] {
+			return n
+		}
+	}
+	return ""
 }
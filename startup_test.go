@@ -0,0 +1,146 @@
+package goboot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartupHooksDependencies(t *testing.T) {
+	a := newTestApp(t)
+
+	var got []string
+	record := func(name string) func() error {
+		return func() error { got = append(got, name); return nil }
+	}
+	a.OnAppStart(record("late"), 5)
+	a.OnAppStartHook(StartupHook{Name: "consumer", After: []string{"redis"}, Func: record("consumer")})
+	a.OnAppStartHook(StartupHook{Name: "redis", Order: 9, Func: record("redis")})
+	a.OnAppStart(record("early"), 0)
+
+	if err := a.Startup(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"early", "late", "redis", "consumer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order %v, want %v", got, want)
+	}
+}
+
+func TestStartupHooksDefaultOrder(t *testing.T) {
+	a := newTestApp(t)
+
+	var got []string
+	record := func(name string) func() error {
+		return func() error { got = append(got, name); return nil }
+	}
+	a.OnAppStart(record("plain1"))
+	a.OnAppStartHook(StartupHook{Name: "named1", Func: record("named1")})
+	a.OnAppStartContext(func(context.Context) error { return record("plain2")() })
+	a.OnAppStartHook(StartupHook{Name: "named2", Order: DefaultStartupOrder, Func: record("named2")})
+	a.OnAppStart(record("first"), 0)
+
+	if err := a.Startup(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first", "plain1", "named1", "plain2", "named2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order %v, want %v", got, want)
+	}
+}
+
+func TestStartupHooksErrors(t *testing.T) {
+	a := newTestApp(t)
+
+	ran := false
+	boom := errors.New("boom")
+	a.OnAppStartHook(StartupHook{Name: "db", Func: func() error { return boom }})
+	a.OnAppStartHook(StartupHook{Name: "repo", After: []string{"db"}, Func: func() error { return nil }})
+	a.OnAppStartHook(StartupHook{Name: "cache", Func: func() error { panic("no redis") }})
+	a.OnAppStartHook(StartupHook{Name: "metrics", Func: func() error { ran = true; return nil }})
+
+	err := a.runStartupHooks(context.Background())
+	if !errors.Is(err, boom) {
+		t.Error("expected boom in", err)
+	}
+	if !ran {
+		t.Error("independent hook did not run")
+	}
+	for _, s := range []string{"repo: skipped, db failed", "cache: panic: no redis"} {
+		if err == nil || !strings.Contains(err.Error(), s) {
+			t.Errorf("%q not in %v", s, err)
+		}
+	}
+}
+
+func TestStartupHooksCycle(t *testing.T) {
+	a := newTestApp(t)
+
+	nop := func() error { return nil }
+	a.OnAppStartHook(StartupHook{Name: "a", After: []string{"b"}, Func: nop})
+	a.OnAppStartHook(StartupHook{Name: "b", After: []string{"a"}, Func: nop})
+
+	if err := a.runStartupHooks(context.Background()); err == nil || !strings.Contains(err.Error(), "circular") {
+		t.Error(err)
+	}
+}
+
+func TestStartupParallelPhase(t *testing.T) {
+	a := newTestApp(t)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	slow := func(ctx context.Context) error {
+		mu.Lock()
+		if running++; running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+	for _, name := range []string{"redis", "db", "mq"} {
+		a.OnAppStartHook(StartupHook{Name: name, Parallel: true, FuncContext: slow})
+	}
+
+	if err := a.runStartupHooks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if maxRunning != 3 {
+		t.Errorf("%d hooks ran concurrently, want 3", maxRunning)
+	}
+}
+
+func TestStartupParallelPhaseFails(t *testing.T) {
+	a := newTestApp(t)
+
+	cancelled := make(chan error, 1)
+	a.OnAppStartHook(StartupHook{Name: "redis", Parallel: true, Timeout: 10 * time.Millisecond,
+		FuncContext: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+	a.OnAppStartHook(StartupHook{Name: "db", Parallel: true,
+		FuncContext: func(ctx context.Context) error {
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return ctx.Err()
+		}})
+	a.OnAppStartHook(StartupHook{Name: "repo", After: []string{"db"}, Func: func() error { return nil }})
+
+	err := a.runStartupHooks(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected redis timeout in", err)
+	}
+	if !errors.Is(<-cancelled, context.Canceled) {
+		t.Error("db was not cancelled")
+	}
+	if !strings.Contains(err.Error(), "repo: skipped, db failed") {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,233 @@
+package goboot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/e2u/goboot/jobs"
+)
+
+// Defaults for the http.upload.* and http.body.* keys.
+const (
+	DefaultUploadMaxMemory  = 32 << 20
+	DefaultBodyMaxSize      = 32 << 20
+	DefaultUploadTempMaxAge = time.Hour
+)
+
+// UploadTempPattern prefixes the temp files holding uploads bound to
+// *os.File, see SweepUploads.
+const UploadTempPattern = "revel-upload"
+
+// UploadLimits bounds the request bodies read by ParseParams and
+// StreamMultipart.
+type UploadLimits struct {
+	MaxMemory   int64    // multipart bytes kept in memory, the rest goes to temp files
+	MaxBodySize int64    // request body bytes, 0 for no limit
+	MaxFileSize int64    // bytes of each uploaded file, 0 for no limit
+	Types       []string // media types of uploaded files, e.g. image/*, empty allows any
+}
+
+// ParamsError is a request refused while reading its params, Status is the
+// HTTP status to answer with: 413 for a body or file over the limits, 415 for
+// a file type not allowed, 400 for a malformed body.
+type ParamsError struct {
+	Status int
+	Err    error
+}
+
+func (e *ParamsError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParamsError) Unwrap() error {
+	return e.Err
+}
+
+// ParseParams parses the params of req with the upload limits of the App
+// config, see UploadLimits.ParseParams. The params are bound with the App
+// Binders and log to the App Log unless they have their own.
+func (a *App) ParseParams(params *Params, req *http.Request) error {
+	if params.Binders == nil {
+		params.Binders = a.Binders
+	}
+	if params.Log == nil {
+		params.Log = a.Log
+	}
+	return a.Config.UploadLimits().ParseParams(params, req)
+}
+
+// StreamMultipart streams the multipart body of req with the upload limits of
+// the App config, see UploadLimits.StreamMultipart.
+func (a *App) StreamMultipart(req *http.Request, fn func(*UploadPart) error) error {
+	return a.Config.UploadLimits().StreamMultipart(req, fn)
+}
+
+// StreamMultipart streams the multipart body of req with the upload limits of
+// Config, see UploadLimits.StreamMultipart.
+func StreamMultipart(req *http.Request, fn func(*UploadPart) error) error {
+	return Config.UploadLimits().StreamMultipart(req, fn)
+}
+
+// limitBody caps the body of req at MaxBodySize, it returns a 413 error when
+// the announced length is already over.
+func (l UploadLimits) limitBody(req *http.Request) error {
+	if l.MaxBodySize <= 0 || req.Body == nil {
+		return nil
+	}
+	if req.ContentLength > l.MaxBodySize {
+		return &ParamsError{http.StatusRequestEntityTooLarge, fmt.Errorf("request body of %d bytes exceeds %d bytes", req.ContentLength, l.MaxBodySize)}
+	}
+	req.Body = http.MaxBytesReader(nil, req.Body, l.MaxBodySize)
+	return nil
+}
+
+// checkFile checks the size and the media type of an uploaded file.
+func (l UploadLimits) checkFile(field, filename string, size int64, contentType string) error {
+	if l.MaxFileSize > 0 && size > l.MaxFileSize {
+		return &ParamsError{http.StatusRequestEntityTooLarge, fmt.Errorf("file %s of field %s exceeds %d bytes", filename, field, l.MaxFileSize)}
+	}
+	if len(l.Types) == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+	for _, t := range l.Types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+			return nil
+		}
+	}
+	return &ParamsError{http.StatusUnsupportedMediaType, fmt.Errorf("file %s of field %s has type %s, not allowed", filename, field, mediaType)}
+}
+
+// bodyError classifies an error reading a request body.
+func bodyError(err error) error {
+	var pe *ParamsError
+	if errors.As(err, &pe) {
+		return pe
+	}
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return &ParamsError{http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", mbe.Limit)}
+	}
+	return &ParamsError{http.StatusBadRequest, err}
+}
+
+// UploadPart is a part of a multipart body streamed by StreamMultipart.
+// Reading a file part past MaxFileSize fails with a 413 *ParamsError.
+type UploadPart struct {
+	*multipart.Part
+	limit int64 // bytes left to read, -1 for no limit
+	name  string
+}
+
+func (p *UploadPart) Read(b []byte) (int, error) {
+	if p.limit < 0 {
+		return p.Part.Read(b)
+	}
+	if int64(len(b)) > p.limit+1 {
+		b = b[:p.limit+1]
+	}
+	n, err := p.Part.Read(b)
+	if int64(n) > p.limit {
+		n = int(p.limit)
+		err = &ParamsError{http.StatusRequestEntityTooLarge, fmt.Errorf("file %s of field %s exceeds the size limit", p.name, p.FormName())}
+	}
+	p.limit -= int64(n)
+	return n, err
+}
+
+// StreamMultipart calls fn for each part of the multipart body of req as it
+// is read, nothing is buffered in memory or written to temp files. Form
+// values are the parts without a FileName. The body is capped at MaxBodySize,
+// file parts at MaxFileSize and their types are checked before fn is called.
+//
+// Parts must be read within fn. An error from fn stops the streaming and is
+// returned, read errors are *ParamsError.
+func (l UploadLimits) StreamMultipart(req *http.Request, fn func(*UploadPart) error) error {
+	if err := l.limitBody(req); err != nil {
+		return err
+	}
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return &ParamsError{http.StatusBadRequest, err}
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return bodyError(err)
+		}
+
+		up := &UploadPart{Part: part, limit: -1, name: part.FileName()}
+		if up.name != "" {
+			if err := l.checkFile(part.FormName(), up.name, 0, part.Header.Get("Content-Type")); err != nil {
+				part.Close()
+				return err
+			}
+			if l.MaxFileSize > 0 {
+				up.limit = l.MaxFileSize
+			}
+		}
+		err = fn(up)
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// SweepUploads removes the upload temp files of dir, os.TempDir() when empty,
+// modified more than maxAge ago. Params.Close removes the files of handled
+// requests, this catches the ones left behind by a crash or a handler not
+// closing its params. It returns the number of files removed.
+func SweepUploads(dir string, maxAge time.Duration) (int, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	names, err := filepath.Glob(filepath.Join(dir, UploadTempPattern+"*"))
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	deadline := time.Now().Add(-maxAge)
+	for _, name := range names {
+		fi, err := os.Stat(name)
+		if err != nil || !fi.Mode().IsRegular() || fi.ModTime().After(deadline) {
+			continue
+		}
+		if err := os.Remove(name); err == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// startUploadSweeper schedules SweepUploads every http.upload.tmp_max_age,
+// an hour by default, 0 or a negative value disables it.
+func (a *App) startUploadSweeper() {
+	maxAge := a.Config.MustDuration(IniHttpUploadTempMaxAge, DefaultUploadTempMaxAge)
+	if maxAge <= 0 {
+		return
+	}
+	a.Scheduler.Every(maxAge, jobs.Func(func() {
+		n, err := SweepUploads("", maxAge)
+		if err != nil {
+			a.Log.Warning("sweep uploads:", err)
+		} else if n > 0 {
+			a.Log.Infof("removed %d upload temp files older than %s", n, maxAge)
+		}
+	}))
+}
@@ -0,0 +1,200 @@
+package goboot
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testMultipart returns a multipart body with a name field and a file of size
+// bytes and type contentType.
+func testMultipart(t *testing.T, size int, contentType string) (string, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", "rob")
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="a.bin"`)
+	h.Set("Content-Type", contentType)
+	fw, err := mw.CreatePart(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(bytes.Repeat([]byte("x"), size))
+	mw.Close()
+	return buf.String(), mw.FormDataContentType()
+}
+
+func paramsStatus(err error) int {
+	var pe *ParamsError
+	if errors.As(err, &pe) {
+		return pe.Status
+	}
+	return 0
+}
+
+func TestUploadLimits(t *testing.T) {
+	withTestLog(t)
+	c := NewConfigWithoutFile("test")
+	if l := c.UploadLimits(); l.MaxMemory != DefaultUploadMaxMemory || l.MaxBodySize != DefaultBodyMaxSize || l.MaxFileSize != 0 || l.Types != nil {
+		t.Errorf("defaults = %+v", l)
+	}
+	c.RunModeSection.NewKey(IniHttpBodyMaxSize, "-1")
+	c.RunModeSection.NewKey(IniHttpUploadMaxFile, "100")
+	c.RunModeSection.NewKey(IniHttpUploadTypes, "image/*, text/plain")
+	if l := c.UploadLimits(); l.MaxBodySize != 0 || l.MaxFileSize != 100 || len(l.Types) != 2 {
+		t.Errorf("limits = %+v", l)
+	}
+
+	l := UploadLimits{MaxMemory: 1024, MaxBodySize: 1000, MaxFileSize: 100, Types: []string{"image/*", "text/plain"}}
+	cases := []struct {
+		size        int
+		contentType string
+		status      int
+	}{
+		{100, "image/png", 0},
+		{10, "text/plain; charset=utf-8", 0},
+		{101, "image/png", 413},
+		{10, "application/pdf", 415},
+		{2000, "image/png", 413},
+	}
+	for _, c := range cases {
+		body, ct := testMultipart(t, c.size, c.contentType)
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", ct)
+		r.ContentLength = -1
+		p := &Params{}
+		err := l.ParseParams(p, r)
+		if paramsStatus(err) != c.status {
+			t.Errorf("%d bytes of %s: %v", c.size, c.contentType, err)
+		}
+		if err == nil && (p.Get("name") != "rob" || p.Files["file"][0].Size != int64(c.size)) {
+			t.Errorf("%d bytes of %s: params %v", c.size, c.contentType, p.Values)
+		}
+	}
+
+	// The body is cut while reading.
+	r := httptest.NewRequest("POST", "/?q=1", strings.NewReader(`{"name":"`+strings.Repeat("x", 2000)+`"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.ContentLength = -1
+	p := &Params{}
+	if err := l.ParseParams(p, r); paramsStatus(err) != 413 || p.JSON != nil || p.Get("q") != "1" {
+		t.Errorf("json over limit: %v", err)
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	l := UploadLimits{MaxFileSize: 100, Types: []string{"image/png"}}
+	body, ct := testMultipart(t, 100, "image/png")
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", ct)
+	got := map[string]int{}
+	err := l.StreamMultipart(r, func(p *UploadPart) error {
+		b, err := io.ReadAll(p)
+		got[p.FormName()] = len(b)
+		return err
+	})
+	if err != nil || got["name"] != 3 || got["file"] != 100 {
+		t.Errorf("got %v, %v", got, err)
+	}
+
+	for _, c := range []struct {
+		size        int
+		contentType string
+		status      int
+	}{{101, "image/png", 413}, {1, "image/gif", 415}} {
+		body, ct := testMultipart(t, c.size, c.contentType)
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", ct)
+		err := l.StreamMultipart(r, func(p *UploadPart) error {
+			_, err := io.Copy(io.Discard, p)
+			return err
+		})
+		if paramsStatus(err) != c.status {
+			t.Errorf("%d bytes of %s: %v", c.size, c.contentType, err)
+		}
+	}
+
+	errStop := errors.New("stop")
+	r = httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", ct)
+	if err := l.StreamMultipart(r, func(*UploadPart) error { return errStop }); err != errStop {
+		t.Errorf("callback error = %v", err)
+	}
+}
+
+func TestParamsClose(t *testing.T) {
+	withTestLog(t)
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, field := range []string{"file", "data", "reader", "list[]", "list[]"} {
+		fw, _ := mw.CreateFormFile(field, "a.txt")
+		fw.Write(bytes.Repeat([]byte("x"), 64))
+	}
+	mw.Close()
+	r := httptest.NewRequest("POST", "/", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	// No memory, every file goes to a multipart temp file.
+	p := &Params{}
+	if err := (UploadLimits{MaxMemory: 1}).ParseParams(p, r); err != nil {
+		t.Fatal(err)
+	}
+	var f *os.File
+	var data []byte
+	var reader io.ReadSeeker
+	var list []*os.File
+	p.Bind(&f, "file")
+	p.Bind(&data, "data")
+	p.Bind(&reader, "reader")
+	p.Bind(&list, "list")
+	if f == nil || len(data) != 64 || reader == nil || len(list) != 2 {
+		t.Fatalf("bound %v %d %v %v", f, len(data), reader, list)
+	}
+	if n := len(p.files) + len(p.tmpFiles); n != 4 {
+		t.Errorf("%d files tracked", n)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range append(list, f) {
+		if _, err := os.Stat(f.Name()); !os.IsNotExist(err) {
+			t.Errorf("%s not removed: %v", f.Name(), err)
+		}
+	}
+	if _, err := reader.Read(make([]byte, 1)); err == nil {
+		t.Error("reader still open")
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
+
+func TestSweepUploads(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{UploadTempPattern + "1", UploadTempPattern + "2", "other"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, nil, 0600)
+		os.Chtimes(path, old, old)
+	}
+	os.WriteFile(filepath.Join(dir, UploadTempPattern+"3"), nil, 0600)
+
+	n, err := SweepUploads(dir, time.Hour)
+	if err != nil || n != 2 {
+		t.Fatalf("removed %d, %v", n, err)
+	}
+	left, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(left) != 2 {
+		t.Errorf("left %v", left)
+	}
+}
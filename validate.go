@@ -0,0 +1,250 @@
+package goboot
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldError reports a field failing a validation rule, or a parameter that
+// could not be converted to the field type, in which case Rule is "type".
+type FieldError struct {
+	Field string      // path of the field, e.g. Address.City or Items[2].Name
+	Rule  string      // failed rule, e.g. "min"
+	Param string      // rule parameter, e.g. "1" for min=1
+	Value interface{} // rejected value
+}
+
+func (e FieldError) Error() string {
+	rule := e.Rule
+	if e.Param != "" {
+		rule += "=" + e.Param
+	}
+	return fmt.Sprintf("%s: %s: rejected value %v", e.Field, rule, e.Value)
+}
+
+// ValidationErrors lists every field failing validation.
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	s := make([]string, len(errs))
+	for i, e := range errs {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// Validate checks the validate tags of the struct v points to, and of nested
+// structs, slices and maps of structs. It returns ValidationErrors, or an
+// error for an unknown rule or a malformed tag. The rules are
+//
+//	required    the field is not the zero value
+//	omitempty   the other rules are skipped for the zero value
+//	min=n       numbers are at least n, strings, slices and maps have at least n elements
+//	max=n       like min, at most n
+//	email       a string holding a single address, e.g. rob@example.com
+//	oneof=a b   the value is one of the space separated values
+//
+// e.g.
+//
+//	type Signup struct {
+//		Email string `validate:"required,email"`
+//		Age   int    `validate:"omitempty,min=13,max=130"`
+//		Plan  string `validate:"oneof=free pro"`
+//	}
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	var errs ValidationErrors
+	if err := validateValue(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateValue(v reflect.Value, path string, errs *ValidationErrors) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return validateValue(v.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		typ := v.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			// PkgPath is specified to be empty exactly for exported fields.
+			if sf.PkgPath != "" && !sf.Anonymous {
+				continue
+			}
+			fieldPath := joinPath(path, fieldName(sf))
+			if sf.Anonymous {
+				fieldPath = path
+			}
+			if tag := sf.Tag.Get("validate"); tag != "" && tag != "-" {
+				if err := validateField(v.Field(i), fieldPath, tag, errs); err != nil {
+					return err
+				}
+			}
+			if err := validateValue(v.Field(i), fieldPath, errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			if err := validateValue(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k.Interface()), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// fieldName is the name of a struct field in parameters, JSON bodies and
+// validation errors: the name given by its form tag, or its json tag, or the
+// field name.
+func fieldName(sf reflect.StructField) string {
+	if name, _ := fieldTag(sf); name != "" && name != "-" {
+		return name
+	}
+	return sf.Name
+}
+
+// fieldTag returns the name and the omitempty option of the form tag of sf,
+// or of its json tag when there is no form tag.
+func fieldTag(sf reflect.StructField) (name string, omitEmpty bool) {
+	tag, ok := sf.Tag.Lookup("form")
+	if !ok {
+		tag = sf.Tag.Get("json")
+	}
+	opts := strings.Split(tag, ",")
+	for _, o := range opts[1:] {
+		if o == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return opts[0], omitEmpty
+}
+
+// validateField applies the comma separated rules of tag to v.
+func validateField(v reflect.Value, path, tag string, errs *ValidationErrors) error {
+	rules := strings.Split(tag, ",")
+	for _, r := range rules {
+		if r == "omitempty" && v.IsZero() {
+			return nil
+		}
+	}
+
+	for _, r := range rules {
+		rule, param := r, ""
+		if i := strings.Index(r, "="); i >= 0 {
+			rule, param = r[:i], r[i+1:]
+		}
+
+		ok, err := checkRule(v, rule, param)
+		if err != nil {
+			return fmt.Errorf("validate %s: %v", path, err)
+		}
+		if !ok {
+			*errs = append(*errs, FieldError{Field: path, Rule: rule, Param: param, Value: fieldValue(v)})
+		}
+	}
+	return nil
+}
+
+func fieldValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func checkRule(v reflect.Value, rule, param string) (bool, error) {
+	switch rule {
+	case "", "omitempty":
+		return true, nil
+	case "required":
+		return !v.IsZero(), nil
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return true, nil
+		}
+		v = v.Elem()
+	}
+
+	switch rule {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return false, fmt.Errorf("%s needs a number, got %q", rule, param)
+		}
+		n, ok := measure(v)
+		if !ok {
+			return false, fmt.Errorf("%s does not apply to %s", rule, v.Type())
+		}
+		if rule == "min" {
+			return n >= limit, nil
+		}
+		return n <= limit, nil
+	case "email":
+		if v.Kind() != reflect.String {
+			return false, fmt.Errorf("email does not apply to %s", v.Type())
+		}
+		a, err := mail.ParseAddress(v.String())
+		return err == nil && a.Address == v.String(), nil
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, o := range strings.Fields(param) {
+			if s == o {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown rule %q", rule)
+}
+
+// measure returns the number compared by min and max: the value of numbers,
+// the length of strings, slices and maps.
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,81 @@
+package goboot
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type testSignup struct {
+	Email   string        `json:"email" validate:"required,email"`
+	Age     int           `json:"age" validate:"omitempty,min=13,max=130"`
+	Plan    string        `json:"plan" validate:"oneof=free pro"`
+	Tags    []string      `json:"tags" validate:"max=2"`
+	Address testAddress   `json:"address"`
+	Others  []testAddress `json:"others"`
+}
+
+func TestValidate(t *testing.T) {
+	s := testSignup{Email: "rob", Age: 7, Plan: "gold", Tags: []string{"a", "b", "c"}, Others: []testAddress{{City: "x"}, {}}}
+	err := Validate(&s)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Field+" "+e.Rule)
+	}
+	want := []string{"email email", "age min", "plan oneof", "tags max", "address.city required", "others[1].city required"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	s = testSignup{Email: "rob@example.com", Plan: "pro", Address: testAddress{City: "Paris"}}
+	if err := Validate(&s); err != nil {
+		t.Error(err)
+	}
+
+	bad := struct {
+		N int `validate:"between=1"`
+	}{}
+	if err := Validate(&bad); err == nil || !strings.Contains(err.Error(), "unknown rule") {
+		t.Error(err)
+	}
+}
+
+func TestBindStruct(t *testing.T) {
+	withTestLog(t)
+	p := &Params{Values: url.Values{"email": {"rob@example.com"}, "age": {"abc"}, "plan": {"pro"}, "address.city": {"Paris"}}}
+	s := testSignup{Tags: []string{"kept"}}
+	err := p.BindStruct(&s)
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Field != "age" || errs[0].Rule != "type" || errs[0].Value != "abc" {
+		t.Fatalf("errors %v", err)
+	}
+	if s.Email != "rob@example.com" || s.Address.City != "Paris" || len(s.Tags) != 1 {
+		t.Errorf("bound %+v", s)
+	}
+
+	p = &Params{JSON: []byte(`{"email":"nope","plan":"pro","address":{"city":"Oslo"}}`)}
+	if err := p.BindStruct(&s); err == nil || !strings.Contains(err.Error(), "email: email") {
+		t.Error(err)
+	}
+}
+
+func TestBindJSONDoesNotValidate(t *testing.T) {
+	withTestLog(t)
+	p := &Params{JSON: []byte(`{"email":"nope","plan":"gold"}`)}
+	var s testSignup
+	if err := p.BindJSON(&s); err != nil || s.Email != "nope" {
+		t.Fatalf("BindJSON = %v, %+v", err, s)
+	}
+	if _, ok := Validate(&s).(ValidationErrors); !ok {
+		t.Error("Validate accepted the body")
+	}
+}
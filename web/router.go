@@ -0,0 +1,288 @@
+// Package web serves HTTP requests for goboot applications: a router passing
+// the parsed request parameters to handlers, and a server started and stopped
+// with the App.
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/e2u/goboot"
+)
+
+// HandlerFunc handles a request, p holds the query, form, body and route
+// parameters parsed by goboot.ParseParams.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request, p *goboot.Params)
+
+// Route is a registered route, it can be named for Router.URL.
+type Route struct {
+	router   *Router
+	method   string
+	pattern  string
+	segments []string
+	handler  HandlerFunc
+}
+
+// Name names the route for Router.URL, it panics when the name is taken.
+func (rte *Route) Name(name string) *Route {
+	rt := rte.router
+	if _, ok := rt.names[name]; ok {
+		panic("web: route name " + name + " already used")
+	}
+	if rt.names == nil {
+		rt.names = make(map[string]*Route)
+	}
+	rt.names[name] = rte
+	return rte
+}
+
+// Router dispatches requests to the first route matching the method and the
+// path, in registration order.
+//
+// A pattern is a path whose segments may be parameters: {id} matches one
+// segment, {path...} as the last segment matches the rest of the path. The
+// matched values are set in Params.Route, e.g. /users/{id} fills
+// p.Route["id"] and can be bound with p.Bind(&id, "id").
+//
+// Requests whose params can not be parsed are answered with the status of
+// the goboot.ParamsError, e.g. 413 for a body over http.body.max_size. The
+// params are closed when the handler returns, see goboot.Params.Close.
+type Router struct {
+	NotFound http.Handler // defaults to http.NotFound
+	App      *goboot.App  // supplies the upload limits and binders, nil for goboot.Config
+
+	// Binders bind the params of the routed requests instead of the App
+	// binders, e.g. goboot.NewBinderRegistry(app.Binders) to override some.
+	Binders *goboot.BinderRegistry
+
+	routes     []*Route
+	names      map[string]*Route
+	middleware []func(http.Handler) http.Handler
+}
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers h for method and pattern, the method "*" matches any
+// method.
+func (rt *Router) Handle(method, pattern string, h HandlerFunc) *Route {
+	rte := &Route{
+		router:   rt,
+		method:   strings.ToUpper(method),
+		pattern:  pattern,
+		segments: splitPath(pattern),
+		handler:  h,
+	}
+	rt.routes = append(rt.routes, rte)
+	return rte
+}
+
+func (rt *Router) Get(pattern string, h HandlerFunc) *Route {
+	return rt.Handle(http.MethodGet, pattern, h)
+}
+
+func (rt *Router) Post(pattern string, h HandlerFunc) *Route {
+	return rt.Handle(http.MethodPost, pattern, h)
+}
+
+func (rt *Router) Put(pattern string, h HandlerFunc) *Route {
+	return rt.Handle(http.MethodPut, pattern, h)
+}
+
+func (rt *Router) Patch(pattern string, h HandlerFunc) *Route {
+	return rt.Handle(http.MethodPatch, pattern, h)
+}
+
+func (rt *Router) Delete(pattern string, h HandlerFunc) *Route {
+	return rt.Handle(http.MethodDelete, pattern, h)
+}
+
+// URL returns the URL of the route named name. params, a struct or a map, is
+// encoded with the EncodeQuery of the router binders, the ones the handler
+// binds it back with: the values
+// named by the route parameters fill the path, the others make the query
+// string. e.g. with
+//
+//	rt.Get("/users/{id}", showUser).Name("user")
+//
+// rt.URL("user", map[string]interface{}{"id": 42, "tab": "posts"}) returns
+// /users/42?tab=posts.
+func (rt *Router) URL(name string, params interface{}) (string, error) {
+	rte, ok := rt.names[name]
+	if !ok {
+		return "", fmt.Errorf("web: no route named %q", name)
+	}
+	values := rt.paramBinders().EncodeQuery(params)
+	if len(values) > 0 {
+		// Do not modify the url.Values of the caller.
+		values = cloneValues(values)
+	}
+
+	path := make([]string, len(rte.segments))
+	for i, seg := range rte.segments {
+		name, isParam := paramName(seg)
+		if !isParam {
+			path[i] = seg
+			continue
+		}
+		rest := strings.HasSuffix(name, "...") && i == len(rte.segments)-1
+		name = strings.TrimSuffix(name, "...")
+		v := values.Get(name)
+		if v == "" && !rest {
+			return "", fmt.Errorf("web: route %q needs the parameter %s", rte.pattern, name)
+		}
+		delete(values, name)
+		if !rest {
+			path[i] = url.PathEscape(v)
+			continue
+		}
+		parts := strings.Split(v, "/")
+		for j, part := range parts {
+			parts[j] = url.PathEscape(part)
+		}
+		path[i] = strings.Join(parts, "/")
+	}
+
+	u := "/" + strings.Join(path, "/")
+	if len(values) > 0 {
+		u += "?" + values.Encode()
+	}
+	return u, nil
+}
+
+func cloneValues(values url.Values) url.Values {
+	c := make(url.Values, len(values))
+	for k, v := range values {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// Use wraps every request in mw, the first middleware is the outermost.
+func (rt *Router) Use(mw ...func(http.Handler) http.Handler) {
+	rt.middleware = append(rt.middleware, mw...)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var h http.Handler = http.HandlerFunc(rt.dispatch)
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		h = rt.middleware[i](h)
+	}
+	h.ServeHTTP(w, r)
+}
+
+// dispatch runs the matching route, or answers 405 with the allowed methods
+// when only the path matches.
+func (rt *Router) dispatch(w http.ResponseWriter, r *http.Request) {
+	path := splitPath(r.URL.Path)
+	var allowed []string
+	for _, rte := range rt.routes {
+		values, ok := rte.match(path)
+		if !ok {
+			continue
+		}
+		if rte.method != "*" && rte.method != r.Method && !(rte.method == http.MethodGet && r.Method == http.MethodHead) {
+			if !contains(allowed, rte.method) {
+				allowed = append(allowed, rte.method)
+			}
+			continue
+		}
+
+		p := &goboot.Params{Route: values, Binders: rt.paramBinders()}
+		defer p.Close()
+		var err error
+		if rt.App != nil {
+			err = rt.App.ParseParams(p, r)
+		} else {
+			err = goboot.ParseParams(p, r)
+		}
+		if err != nil {
+			status := http.StatusBadRequest
+			var pe *goboot.ParamsError
+			if errors.As(err, &pe) {
+				status = pe.Status
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		rte.handler(w, r, p)
+		return
+	}
+
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if rt.NotFound != nil {
+		rt.NotFound.ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// paramBinders returns the binders of the params, nil for the global ones.
+func (rt *Router) paramBinders() *goboot.BinderRegistry {
+	if rt.Binders == nil && rt.App != nil {
+		return rt.App.Binders
+	}
+	return rt.Binders
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// match returns the route parameters when path matches the pattern.
+func (rte *Route) match(path []string) (url.Values, bool) {
+	var values url.Values
+	for i, seg := range rte.segments {
+		name, isParam := paramName(seg)
+		if isParam && strings.HasSuffix(name, "...") && i == len(rte.segments)-1 {
+			if values == nil {
+				values = make(url.Values)
+			}
+			values.Set(strings.TrimSuffix(name, "..."), strings.Join(path[i:], "/"))
+			return values, true
+		}
+		if i >= len(path) {
+			return nil, false
+		}
+		if !isParam {
+			if seg != path[i] {
+				return nil, false
+			}
+			continue
+		}
+		if path[i] == "" {
+			return nil, false
+		}
+		if values == nil {
+			values = make(url.Values)
+		}
+		values.Set(name, path[i])
+	}
+	return values, len(path) == len(rte.segments)
+}
+
+func paramName(seg string) (string, bool) {
+	if len(seg) > 2 && seg[0] == '{' && seg[len(seg)-1] == '}' {
+		return seg[1 : len(seg)-1], true
+	}
+	return "", false
+}
@@ -0,0 +1,264 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/e2u/goboot"
+	logging "github.com/op/go-logging"
+)
+
+func TestRouter(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request, p *goboot.Params) {
+		var id int
+		p.Bind(&id, "id")
+		io.WriteString(w, "user "+p.Route.Get("id")+" "+p.Get("tab"))
+		if id == 0 {
+			t.Error("id not bound")
+		}
+	})
+	rt.Post("/users", func(w http.ResponseWriter, r *http.Request, p *goboot.Params) {
+		io.WriteString(w, "created "+p.Form.Get("name"))
+	})
+	rt.Handle("*", "/static/{path...}", func(w http.ResponseWriter, r *http.Request, p *goboot.Params) {
+		io.WriteString(w, "static "+p.Route.Get("path"))
+	})
+
+	cases := []struct {
+		method, path, body string
+		code               int
+		want               string
+	}{
+		{"GET", "/users/42?tab=posts", "", 200, "user 42 posts"},
+		{"GET", "/users/42/", "", 200, "user 42 "},
+		{"POST", "/users", "name=rob", 200, "created rob"},
+		{"GET", "/static/css/site.css", "", 200, "static css/site.css"},
+		{"DELETE", "/users/42", "", 405, ""},
+		{"GET", "/users", "", 405, ""},
+		{"GET", "/users/42/posts", "", 404, ""},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		if c.body != "" {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, r)
+		if rec.Code != c.code || c.want != "" && rec.Body.String() != c.want {
+			t.Errorf("%s %s: %d %q, want %d %q", c.method, c.path, rec.Code, rec.Body, c.code, c.want)
+		}
+	}
+}
+
+func TestRouterURL(t *testing.T) {
+	noop := func(http.ResponseWriter, *http.Request, *goboot.Params) {}
+	rt := NewRouter()
+	rt.Get("/users/{id}", noop).Name("user")
+	rt.Get("/files/{path...}", noop).Name("file")
+	rt.Get("/", noop).Name("home")
+
+	type query struct {
+		ID   int      `form:"id"`
+		Tags []string `form:"tags"`
+	}
+	cases := []struct {
+		name   string
+		params interface{}
+		want   string
+	}{
+		{"user", map[string]interface{}{"id": 42, "tab": "posts"}, "/users/42?tab=posts"},
+		{"user", query{ID: 7, Tags: []string{"x"}}, "/users/7?tags%5B0%5D=x"},
+		{"user", url.Values{"id": {"a b/c"}}, "/users/a%20b%2Fc"},
+		{"file", map[string]string{"path": "css/site v2.css"}, "/files/css/site%20v2.css"},
+		{"home", nil, "/"},
+	}
+	for _, c := range cases {
+		got, err := rt.URL(c.name, c.params)
+		if err != nil || got != c.want {
+			t.Errorf("%s %v = %q, %v, want %q", c.name, c.params, got, err, c.want)
+		}
+	}
+
+	if _, err := rt.URL("user", nil); err == nil {
+		t.Error("missing id accepted")
+	}
+	if _, err := rt.URL("nope", nil); err == nil {
+		t.Error("unknown route accepted")
+	}
+
+	// The URL routes back to the route with the same params.
+	var got query
+	rt.Get("/search/{id}", func(w http.ResponseWriter, r *http.Request, p *goboot.Params) {
+		p.BindStruct(&got)
+	}).Name("search")
+	want := query{ID: 3, Tags: []string{"a", "b"}}
+	u, _ := rt.URL("search", want)
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", u, nil))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s bound %+v", u, got)
+	}
+}
+
+func TestRouterBinders(t *testing.T) {
+	upper := goboot.Binder{BindE: goboot.ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		return reflect.ValueOf(strings.ToUpper(val)), nil
+	})}
+	a := goboot.New(goboot.WithConfig(goboot.NewConfigWithoutFile("test")), goboot.WithLogger(logging.MustGetLogger("test")))
+	a.Binders.Register(reflect.TypeOf(""), upper)
+
+	var got string
+	rt := NewRouter()
+	rt.Get("/hello/{name}", func(w http.ResponseWriter, r *http.Request, p *goboot.Params) {
+		p.Bind(&got, "name")
+	})
+	for _, c := range []struct {
+		app     *goboot.App
+		binders *goboot.BinderRegistry
+		want    string
+	}{
+		{nil, nil, "rob"},
+		{a, nil, "ROB"},
+		{a, goboot.NewBinderRegistry(nil), "rob"},
+	} {
+		rt.App, rt.Binders = c.app, c.binders
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/hello/rob", nil))
+		if got != c.want {
+			t.Errorf("bound %q, want %q", got, c.want)
+		}
+	}
+}
+
+// TestRouterAppOnly serves with an App made by New, without goboot.Init or
+// goboot.Log.
+func TestRouterAppOnly(t *testing.T) {
+	rt := NewRouter()
+	rt.App = goboot.New(goboot.WithConfig(goboot.NewConfigWithoutFile("test")), goboot.WithLogger(logging.MustGetLogger("test")))
+	id := -1
+	rt.Get("/u/{id}", func(w http.ResponseWriter, r *http.Request, p *goboot.Params) {
+		p.Bind(&id, "id")
+	})
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest("GET", "/u/abc", nil))
+	if rec.Code != http.StatusOK || id != 0 {
+		t.Errorf("status %d, id %d", rec.Code, id)
+	}
+}
+
+// cents is bound from and unbound to a decimal amount by the router binders
+// of TestRouterURLBinders.
+type cents int
+
+func TestRouterURLBinders(t *testing.T) {
+	decimal := goboot.Binder{
+		BindE: goboot.ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+			f, err := strconv.ParseFloat(val, 64)
+			return reflect.ValueOf(cents(math.Round(f * 100))), err
+		}),
+		Unbind: func(output map[string]string, name string, val interface{}) {
+			output[name] = fmt.Sprintf("%.2f", float64(val.(cents))/100)
+		},
+	}
+	type order struct {
+		ID    cents   `form:"id"`
+		Items []cents `form:"items"`
+		Tip   *cents  `form:"tip"`
+	}
+
+	var got order
+	rt := NewRouter()
+	rt.Binders = goboot.NewBinderRegistry(nil)
+	rt.Binders.Register(reflect.TypeOf(cents(0)), decimal)
+	rt.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request, p *goboot.Params) {
+		if err := p.BindStruct(&got); err != nil {
+			t.Error(err)
+		}
+	}).Name("order")
+
+	tip := cents(50)
+	want := order{ID: 1250, Items: []cents{199, 5}, Tip: &tip}
+	u, err := rt.URL("order", want)
+	if err != nil || u != "/orders/12.50?items%5B0%5D=1.99&items%5B1%5D=0.05&tip=0.50" {
+		t.Fatalf("URL = %s, %v", u, err)
+	}
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", u, nil))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s bound %+v", u, got)
+	}
+}
+
+func TestRouterParamsError(t *testing.T) {
+	cfg := goboot.NewConfigWithoutFile("test")
+	cfg.RunModeSection.NewKey(goboot.IniHttpBodyMaxSize, "8")
+	rt := NewRouter()
+	rt.App = goboot.New(goboot.WithConfig(cfg), goboot.WithLogger(logging.MustGetLogger("test")))
+	rt.Post("/items", func(w http.ResponseWriter, r *http.Request, p *goboot.Params) {
+		io.WriteString(w, "ok")
+	})
+
+	for body, code := range map[string]int{`{}`: 200, `{"name":"too long"}`: 413, `{`: 200} {
+		r := httptest.NewRequest("POST", "/items", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, r)
+		if rec.Code != code {
+			t.Errorf("%s: %d, want %d", body, rec.Code, code)
+		}
+	}
+
+	r := httptest.NewRequest("POST", "/items", strings.NewReader("x"))
+	r.Header.Set("Content-Type", "multipart/form-data")
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, r)
+	if rec.Code != 400 {
+		t.Errorf("malformed multipart: %d", rec.Code)
+	}
+}
+
+func TestServeApp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := goboot.NewConfigWithoutFile("test")
+	cfg.RunModeSection.NewKey(goboot.IniHttpAddr, addr)
+	a := goboot.New(goboot.WithConfig(cfg), goboot.WithLogger(logging.MustGetLogger("test")))
+
+	rt := NewRouter()
+	rt.Get("/ping", func(w http.ResponseWriter, r *http.Request, p *goboot.Params) {
+		io.WriteString(w, "pong")
+	})
+	ServeApp(a, rt)
+	if err := a.Startup(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get("http://" + addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "pong" {
+		t.Errorf("got %q", b)
+	}
+
+	if err := a.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := http.Get("http://" + addr + "/ping"); err == nil {
+		t.Error("server still running after shutdown")
+	}
+}
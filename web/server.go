@@ -0,0 +1,125 @@
+package web
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/e2u/goboot"
+)
+
+// ServerHookName names the startup hook starting the server of Serve.
+const ServerHookName = "web.server"
+
+// serverOrder starts the server after every other startup hook and stops it
+// before every other stop hook, so no request is served while the app is not
+// ready.
+const serverOrder = math.MaxInt32
+
+// Defaults for the http.* keys.
+const (
+	DefaultAddr          = ":8080"
+	DefaultReadTimeout   = 30 * time.Second
+	DefaultHeaderTimeout = 10 * time.Second
+	DefaultWriteTimeout  = 30 * time.Second
+	DefaultIdleTimeout   = 120 * time.Second
+)
+
+// Server is an http.Server configured from the http.* keys, serving TLS when
+// http.tls.cert and http.tls.key are set.
+type Server struct {
+	*http.Server
+	CertFile string
+	KeyFile  string
+	Log      goboot.Logger // receives serve errors, may be nil
+}
+
+// NewServer returns a server for h configured by c.
+func NewServer(c *goboot.ConfigContext, h http.Handler) *Server {
+	return &Server{
+		Server: &http.Server{
+			Addr:              c.MustString(goboot.IniHttpAddr, DefaultAddr),
+			Handler:           h,
+			ReadTimeout:       c.MustDuration(goboot.IniHttpReadTimeout, DefaultReadTimeout),
+			ReadHeaderTimeout: c.MustDuration(goboot.IniHttpHeaderTimeout, DefaultHeaderTimeout),
+			WriteTimeout:      c.MustDuration(goboot.IniHttpWriteTimeout, DefaultWriteTimeout),
+			IdleTimeout:       c.MustDuration(goboot.IniHttpIdleTimeout, DefaultIdleTimeout),
+		},
+		CertFile: c.MustString(goboot.IniHttpTLSCert),
+		KeyFile:  c.MustString(goboot.IniHttpTLSKey),
+	}
+}
+
+// Start listens on Addr and serves in the background. Listen and certificate
+// errors are returned, Addr is updated with the listening address so that
+// port 0 can be used.
+func (s *Server) Start() error {
+	if s.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return fmt.Errorf("http server: %v", err)
+		}
+		if s.TLSConfig == nil {
+			s.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		s.TLSConfig.Certificates = append(s.TLSConfig.Certificates, cert)
+	}
+
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("http server: %v", err)
+	}
+	s.Addr = ln.Addr().String()
+
+	go func() {
+		var err error
+		if s.TLSConfig != nil {
+			err = s.ServeTLS(ln, "", "")
+		} else {
+			err = s.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed && s.Log != nil {
+			s.Log.Error("http server:", err)
+		}
+	}()
+	return nil
+}
+
+// Serve serves h with the default App, see ServeApp.
+func Serve(h http.Handler) {
+	ServeApp(goboot.Default(), h)
+}
+
+// ServeApp starts a Server for h once the other startup hooks of a
+// succeeded, and shuts it down gracefully before the other stop hooks run.
+// Requests are written to the access log and dumped as enabled by the
+// log.dump.http.* keys.
+// In-flight requests get until the app.shutdown.timeout deadline to finish.
+// A *Router without an App is given a.
+func ServeApp(a *goboot.App, h http.Handler) {
+	if rt, ok := h.(*Router); ok && rt.App == nil {
+		rt.App = a
+	}
+	a.OnAppStartHook(goboot.StartupHook{
+		Name:  ServerHookName,
+		Order: serverOrder,
+		Func: func() error {
+			s := NewServer(a.Config, a.AccessLog(a.DumpHandler(h)))
+			s.Log = a.Log
+			if err := s.Start(); err != nil {
+				return err
+			}
+			a.OnAppStopContext(s.Shutdown, serverOrder)
+
+			scheme := "http"
+			if s.CertFile != "" {
+				scheme = "https"
+			}
+			a.Log.Infof("http server listening on %s://%s", scheme, s.Addr)
+			return nil
+		},
+	})
+}